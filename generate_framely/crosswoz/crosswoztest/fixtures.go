@@ -0,0 +1,17 @@
+// Package crosswoztest builds dialogue fixtures for the tests of the packages reading CrossWOZ
+package crosswoztest
+
+import "github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+
+// Turn is a message of the speaker, each act is {Act, Intent, Slot, Value}
+func Turn(speaker string, utterance string, acts ...[4]string) *crosswoz.Message {
+	return &crosswoz.Message{Utterance: utterance, Speaker: speaker, DialogActs: Acts(acts...)}
+}
+
+// Acts are the dialog acts of {Act, Intent, Slot, Value}
+func Acts(acts ...[4]string) (dialogActs []*crosswoz.DialogAct) {
+	for _, act := range acts {
+		dialogActs = append(dialogActs, &crosswoz.DialogAct{Act: act[0], Intent: act[1], Slot: act[2], Value: act[3]})
+	}
+	return dialogActs
+}
@@ -0,0 +1,157 @@
+package crosswoz
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// DialogueIterator gives dialogues one by one, Next returns io.EOF after the last dialogue
+type DialogueIterator interface {
+	Next() (*Dialogue, error)
+}
+
+// DialogueReader decodes a CrossWOZ file ({"id": {...}, ...}) one dialogue at a time,
+// so that train.json never needs to be held in memory as a whole.
+// Dialogues come in the order of the file, not sorted by id like ReadDialogues.
+type DialogueReader struct {
+	decoder *json.Decoder
+	closer  io.Closer
+	file    string // for parse errors, "" if not opened by OpenDialogueReader
+	started bool
+	done    bool
+}
+
+func NewDialogueReader(r io.Reader) *DialogueReader {
+	return &DialogueReader{
+		decoder: json.NewDecoder(r),
+	}
+}
+
+// OpenDialogueReader opens a plain json, .gz or .zip dialogue file for streaming,
+// the reader should be closed after use
+func OpenDialogueReader(inputFileFullPath string) (*DialogueReader, error) {
+	f, err := OpenDialogueFile(inputFileFullPath)
+	if err != nil {
+		return nil, err
+	}
+	reader := NewDialogueReader(f)
+	reader.closer = f
+	reader.file = inputFileFullPath
+	return reader, nil
+}
+
+// Close closes the underlying file if the reader was opened by OpenDialogueReader
+func (reader *DialogueReader) Close() error {
+	reader.done = true
+	if reader.closer == nil {
+		return nil
+	}
+	return reader.closer.Close()
+}
+
+// NextRaw decodes the next dialogue without transforming it
+func (reader *DialogueReader) NextRaw() (string, *RawDialogue, error) {
+	if reader.done {
+		return "", nil, io.EOF
+	}
+	if !reader.started {
+		if err := reader.expectDelim('{'); err != nil {
+			return "", nil, reader.fail(err)
+		}
+		reader.started = true
+	}
+	if !reader.decoder.More() {
+		if err := reader.expectDelim('}'); err != nil {
+			return "", nil, reader.fail(err)
+		}
+		reader.done = true
+		return "", nil, io.EOF
+	}
+	token, err := reader.decoder.Token()
+	if err != nil {
+		return "", nil, reader.fail(err)
+	}
+	dialogID, ok := token.(string)
+	if !ok {
+		return "", nil, reader.fail(fmt.Errorf("expect dialogue id, got %v", token))
+	}
+	var rawDialogue *RawDialogue
+	if err := reader.decoder.Decode(&rawDialogue); err != nil {
+		return "", nil, reader.fail(fmt.Errorf("failed to decode dialogue %s, err: %w", dialogID, err))
+	}
+	return dialogID, rawDialogue, nil
+}
+
+// Next decodes and transforms the next dialogue.
+// A *ParseError only concerns the current dialogue, the following ones can still be read.
+func (reader *DialogueReader) Next() (*Dialogue, error) {
+	dialogID, rawDialogue, err := reader.NextRaw()
+	if err != nil {
+		return nil, err
+	}
+	dialogue, err := ConvertDialogue(dialogID, rawDialogue)
+	if err != nil {
+		return nil, inFile(err, reader.file)
+	}
+	return dialogue, nil
+}
+
+func (reader *DialogueReader) expectDelim(delim json.Delim) error {
+	token, err := reader.decoder.Token()
+	if err != nil {
+		return err
+	}
+	if d, ok := token.(json.Delim); !ok || d != delim {
+		return fmt.Errorf("expect %v, got %v", delim, token)
+	}
+	return nil
+}
+
+// fail stops the reader, the position in the stream is unknown after a decoding error
+func (reader *DialogueReader) fail(err error) error {
+	reader.done = true
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// SliceIterator iterates over dialogues which are already in memory
+type SliceIterator struct {
+	dialogues []*Dialogue
+	next      int
+}
+
+func NewSliceIterator(dialogues []*Dialogue) *SliceIterator {
+	return &SliceIterator{dialogues: dialogues}
+}
+
+func (it *SliceIterator) Next() (*Dialogue, error) {
+	if it.next >= len(it.dialogues) {
+		return nil, io.EOF
+	}
+	it.next++
+	return it.dialogues[it.next-1], nil
+}
+
+// ForEachDialogue calls f for every dialogue, dialogues with a *ParseError are passed to onParseError
+// (or returned as error if onParseError is nil)
+func ForEachDialogue(it DialogueIterator, onParseError func(err *ParseError), f func(dialogue *Dialogue)) error {
+	for {
+		dialogue, err := it.Next()
+		if err == io.EOF {
+			return nil
+		}
+		var parseErr *ParseError
+		if errors.As(err, &parseErr) && onParseError != nil {
+			onParseError(parseErr)
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		f(dialogue)
+	}
+}
@@ -0,0 +1,59 @@
+package crosswoz
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestDialogueReader(t *testing.T) {
+	reader := NewDialogueReader(strings.NewReader(badDialogues))
+	var loaded []string
+	var parseErrors int
+	err := ForEachDialogue(reader, func(err *ParseError) {
+		parseErrors++
+	}, func(dialogue *Dialogue) {
+		loaded = append(loaded, dialogue.DialogueID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded) != 1 || loaded[0] != "1" || parseErrors != 1 {
+		t.Errorf("expect dialogue 1 loaded and dialogue 2 failed, got %v, %d", loaded, parseErrors)
+	}
+	if _, err := reader.Next(); err != io.EOF {
+		t.Errorf("expect io.EOF after the last dialogue, got %v", err)
+	}
+
+	reader = NewDialogueReader(strings.NewReader(`{"1": {"sys-usr": [1, 2]}, "2": `))
+	if _, err := reader.Next(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reader.Next(); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("expect io.ErrUnexpectedEOF for truncated file, got %v", err)
+	}
+}
+
+func TestDialogueReaderMatchesReadDialogues(t *testing.T) {
+	fileName := "../../data/crosswoz/demo10034.json"
+	expected, err := LoadDialogues(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reader, err := OpenDialogueReader(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	i := 0
+	err = ForEachDialogue(reader, nil, func(dialogue *Dialogue) {
+		if i >= len(expected) || dialogue.DialogueID != expected[i].DialogueID || len(dialogue.Turns) != len(expected[i].Turns) {
+			t.Errorf("%d 'th dialogue differs", i)
+		}
+		i++
+	})
+	if err != nil || i != len(expected) {
+		t.Errorf("expect %d dialogues, got %d, err: %v", len(expected), i, err)
+	}
+}
@@ -0,0 +1,211 @@
+package crosswoz
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// kinds of references between sub-goals
+const (
+	// "id=N": the value is the 名称 of sub-goal N, used by 出发地/目的地 of 地铁 and 出租
+	RefName = "name"
+	// "出现在id=N的周边X里": the value is one of the 周边X of sub-goal N
+	RefNearby = "nearby"
+)
+
+var (
+	regRefName   = regexp.MustCompile(`^id=(\d+)$`)
+	regRefNearby = regexp.MustCompile(`^出现在id=(\d+)的周边(\S+?)里$`)
+)
+
+// GoalReference is a dependency edge between two sub-goals, From can only be fulfilled after To
+type GoalReference struct {
+	From         int    // id of the sub-goal holding the reference
+	Slot         string // slot of From whose value is the reference
+	To           int    // id of the referenced sub-goal
+	Kind         string // RefName or RefNearby
+	NearbyDomain string // X of 周边X, only for RefNearby
+}
+
+// ParseGoalReference parses values like "id=2" and "出现在id=3的周边餐馆里", ok is false for normal values
+func ParseGoalReference(value string) (ref *GoalReference, ok bool) {
+	if m := regRefName.FindStringSubmatch(value); m != nil {
+		to, _ := strconv.Atoi(m[1])
+		return &GoalReference{To: to, Kind: RefName}, true
+	}
+	if m := regRefNearby.FindStringSubmatch(value); m != nil {
+		to, _ := strconv.Atoi(m[1])
+		return &GoalReference{To: to, Kind: RefNearby, NearbyDomain: m[2]}, true
+	}
+	return nil, false
+}
+
+type GoalSlot struct {
+	*Slot
+	IsRequest bool           // the value is empty, the user wants to know it
+	Ref       *GoalReference // nil if the value is not a reference to another sub-goal
+}
+
+// IsConstraint if the user tells the value (or a reference to it) to the system
+func (slot *GoalSlot) IsConstraint() bool {
+	return !slot.IsRequest
+}
+
+// SubGoal is all the slots of the same id and domain
+type SubGoal struct {
+	ID     int
+	Domain string
+	Slots  []*GoalSlot
+}
+
+func (subGoal *SubGoal) Constraints() (slots []*GoalSlot) {
+	for _, slot := range subGoal.Slots {
+		if slot.IsConstraint() {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+func (subGoal *SubGoal) Requests() (slots []*GoalSlot) {
+	for _, slot := range subGoal.Slots {
+		if slot.IsRequest {
+			slots = append(slots, slot)
+		}
+	}
+	return slots
+}
+
+func (subGoal *SubGoal) Slot(name string) *GoalSlot {
+	for _, slot := range subGoal.Slots {
+		if slot.Name == name {
+			return slot
+		}
+	}
+	return nil
+}
+
+type Goal struct {
+	SubGoals   []*SubGoal       // sorted by id
+	References []*GoalReference // sorted by From, To
+}
+
+// NewGoal groups the slots by sub-goal and resolves the references between sub-goals,
+// dialogID is only used in the *ParseError for a reference to an unknown sub-goal
+func NewGoal(slots []*Slot, dialogID string) (*Goal, error) {
+	goal := &Goal{}
+	subGoals := make(map[string]*SubGoal)
+	refSlotIdx := make(map[*GoalReference]int)
+	for i, slot := range slots {
+		key := strconv.Itoa(slot.ID) + "." + slot.Group
+		subGoal, ok := subGoals[key]
+		if !ok {
+			subGoal = &SubGoal{ID: slot.ID, Domain: slot.Group}
+			subGoals[key] = subGoal
+			goal.SubGoals = append(goal.SubGoals, subGoal)
+		}
+		goalSlot := &GoalSlot{Slot: slot, IsRequest: isEmptySlotValue(slot.Values)}
+		if slot.Values.Single != nil {
+			if ref, ok := ParseGoalReference(*slot.Values.Single); ok {
+				ref.From = slot.ID
+				ref.Slot = slot.Name
+				goalSlot.Ref = ref
+				refSlotIdx[ref] = i
+				goal.References = append(goal.References, ref)
+			}
+		}
+		subGoal.Slots = append(subGoal.Slots, goalSlot)
+	}
+	sort.SliceStable(goal.SubGoals, func(i, j int) bool {
+		return goal.SubGoals[i].ID < goal.SubGoals[j].ID
+	})
+	sort.SliceStable(goal.References, func(i, j int) bool {
+		if goal.References[i].From == goal.References[j].From {
+			return goal.References[i].To < goal.References[j].To
+		}
+		return goal.References[i].From < goal.References[j].From
+	})
+	for _, ref := range goal.References {
+		if len(goal.SubGoalsByID(ref.To)) == 0 {
+			return nil, &ParseError{
+				DialogueID: dialogID,
+				Section:    "goal",
+				MessageIdx: -1,
+				SlotIdx:    refSlotIdx[ref],
+				Field:      "value",
+				Msg:        fmt.Sprintf("sub-goal %d.%s refers to unknown sub-goal %d", ref.From, ref.Slot, ref.To),
+			}
+		}
+	}
+	return goal, nil
+}
+
+func isEmptySlotValue(values *SlotValues) bool {
+	if values.Single != nil {
+		return *values.Single == ""
+	}
+	return values.Multi == nil || len(*values.Multi) == 0
+}
+
+// SubGoalsByID normally returns one sub-goal, ids are shared by domains only in bad data
+func (goal *Goal) SubGoalsByID(id int) (subGoals []*SubGoal) {
+	for _, subGoal := range goal.SubGoals {
+		if subGoal.ID == id {
+			subGoals = append(subGoals, subGoal)
+		}
+	}
+	return subGoals
+}
+
+// Dependencies are the references from the sub-goal to others
+func (goal *Goal) Dependencies(id int) (refs []*GoalReference) {
+	for _, ref := range goal.References {
+		if ref.From == id {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Dependents are the references from others to the sub-goal
+func (goal *Goal) Dependents(id int) (refs []*GoalReference) {
+	for _, ref := range goal.References {
+		if ref.To == id {
+			refs = append(refs, ref)
+		}
+	}
+	return refs
+}
+
+// Domains of the sub-goals in the order of ids, without duplication
+func (goal *Goal) Domains() []string {
+	var domains []string
+	seen := make(map[string]bool)
+	for _, subGoal := range goal.SubGoals {
+		if !seen[subGoal.Domain] {
+			seen[subGoal.Domain] = true
+			domains = append(domains, subGoal.Domain)
+		}
+	}
+	return domains
+}
+
+// ParsedGoal is the typed model of dialogue.Goal
+func (dialogue *Dialogue) ParsedGoal() (*Goal, error) {
+	return NewGoal(dialogue.Slots, dialogue.DialogueID)
+}
+
+// ParsedFinalGoal is the typed model of dialogue.FinalGoal, the references are already filled by real values in it
+func (dialogue *Dialogue) ParsedFinalGoal() (*Goal, error) {
+	var slots []*Slot
+	for i, rawSlot := range dialogue.FinalGoal {
+		slot, err := DecodeSlot(rawSlot, dialogue.DialogueID, "final_goal", -1, i)
+		if err != nil {
+			return nil, err
+		}
+		slots = append(slots, slot)
+	}
+	return NewGoal(slots, dialogue.DialogueID)
+}
@@ -0,0 +1,59 @@
+package crosswoz
+
+import (
+	"testing"
+)
+
+func TestParsedGoal(t *testing.T) {
+	dialogues, err := LoadDialogues("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	goal, err := dialogues[0].ParsedGoal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(goal.SubGoals) != 3 || goal.SubGoals[1].ID != 2 || goal.SubGoals[1].Domain != "景点" {
+		t.Fatalf("unexpected sub-goals: %+v", goal.SubGoals)
+	}
+	restaurant := goal.SubGoals[0]
+	if len(restaurant.Constraints()) != 2 || len(restaurant.Requests()) != 3 {
+		t.Errorf("expect 2 constraints and 3 requests in sub-goal 1, got %d %d", len(restaurant.Constraints()), len(restaurant.Requests()))
+	}
+	if !restaurant.Slot("周边景点").IsRequest {
+		t.Error("empty list should be a request")
+	}
+	refs := goal.Dependencies(2)
+	if len(refs) != 1 || refs[0].To != 1 || refs[0].Kind != RefNearby || refs[0].NearbyDomain != "景点" || refs[0].Slot != "名称" {
+		t.Errorf("unexpected dependencies of sub-goal 2: %+v", refs)
+	}
+	if len(goal.Dependents(1)) != 1 || len(goal.Dependents(3)) != 0 {
+		t.Error("unexpected dependents")
+	}
+
+	finalGoal, err := dialogues[0].ParsedFinalGoal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(finalGoal.References) != 0 {
+		t.Error("final goal should not have references")
+	}
+}
+
+func TestParseGoalReference(t *testing.T) {
+	if ref, ok := ParseGoalReference("id=12"); !ok || ref.To != 12 || ref.Kind != RefName {
+		t.Errorf("failed to parse id=12: %+v", ref)
+	}
+	if ref, ok := ParseGoalReference("出现在id=3的周边餐馆里"); !ok || ref.To != 3 || ref.NearbyDomain != "餐馆" {
+		t.Errorf("failed to parse nearby reference: %+v", ref)
+	}
+	if _, ok := ParseGoalReference("4.5分以上"); ok {
+		t.Error("normal value is not a reference")
+	}
+
+	bad := []*Slot{{ID: 1, Group: "地铁", Name: "出发地", Values: &SlotValues{Single: new(string)}}}
+	*bad[0].Values.Single = "id=2"
+	if _, err := NewGoal(bad, "bad"); err == nil {
+		t.Error("expect error for reference to unknown sub-goal")
+	}
+}
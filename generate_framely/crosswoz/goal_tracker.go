@@ -0,0 +1,156 @@
+package crosswoz
+
+import (
+	"encoding/json"
+	"strconv"
+)
+
+// GoalProgress is the progress of the user goal after a user turn
+type GoalProgress struct {
+	TurnIdx          int // index in dialogue.Turns
+	Utterance        string
+	NewlyFilled      []string // slots mentioned by the user for the first time, as id.domain.slot
+	ValueChanged     []string // slots whose value changed, normally answered by the system in the previous turn
+	ActiveSubGoal    int      // id of the sub-goal the user is working on, 0 before the first one
+	ActiveDomain     string
+	FilledSlots      int
+	TotalSlots       int
+	FinalGoalReached bool // every slot is filled and has the value of final_goal
+}
+
+// GoalTimeline is the progress of all the user turns in a dialogue
+type GoalTimeline struct {
+	DialogueID         string
+	Type               string
+	SubGoals           int
+	FinalGoalReachedAt int // turn index of the first user turn reaching final_goal, -1 if never
+	Turns              []*GoalProgress
+}
+
+// GoalTracker replays the user_state of user turns in order.
+// Slot.Filled in user_state means the user has already informed or requested the slot,
+// the value of a requested slot is updated once the system answered it.
+type GoalTracker struct {
+	previous  map[string]*Slot
+	finalGoal map[string]string
+	active    *Slot
+}
+
+// NewGoalTracker starts from the initial goal of the dialogue, nothing is filled yet
+func NewGoalTracker(dialogue *Dialogue) (*GoalTracker, error) {
+	tracker := &GoalTracker{
+		previous:  make(map[string]*Slot),
+		finalGoal: make(map[string]string),
+	}
+	for _, slot := range dialogue.Slots {
+		tracker.previous[goalSlotKey(slot)] = slot
+	}
+	for i, rawSlot := range dialogue.FinalGoal {
+		slot, err := DecodeSlot(rawSlot, dialogue.DialogueID, "final_goal", -1, i)
+		if err != nil {
+			return nil, err
+		}
+		tracker.finalGoal[goalSlotKey(slot)] = slotValueString(slot)
+	}
+	return tracker, nil
+}
+
+// Replay the user state of a user turn, the turns must be replayed in order
+func (tracker *GoalTracker) Replay(turnIdx int, turn *Message) *GoalProgress {
+	progress := &GoalProgress{
+		TurnIdx:    turnIdx,
+		Utterance:  turn.Utterance,
+		TotalSlots: len(turn.UserState),
+	}
+	reached := len(turn.UserState) > 0 && len(turn.UserState) == len(tracker.finalGoal)
+	newlyFilledBySubGoal := make(map[int]int)
+	var firstChanged *Slot
+	current := make(map[string]*Slot)
+	for _, slot := range turn.UserState {
+		key := goalSlotKey(slot)
+		current[key] = slot
+		prev := tracker.previous[key]
+		if slot.Filled {
+			progress.FilledSlots++
+			if prev == nil || !prev.Filled {
+				progress.NewlyFilled = append(progress.NewlyFilled, key)
+				newlyFilledBySubGoal[slot.ID]++
+			}
+		}
+		if prev != nil && slotValueString(prev) != slotValueString(slot) {
+			progress.ValueChanged = append(progress.ValueChanged, key)
+			if firstChanged == nil {
+				firstChanged = slot
+			}
+		}
+		if finalValue, ok := tracker.finalGoal[key]; !ok || !slot.Filled || finalValue != slotValueString(slot) {
+			reached = false
+		}
+	}
+	// the sub-goal with most newly filled slots, otherwise the one just answered, otherwise unchanged
+	best := 0
+	for _, slot := range turn.UserState {
+		if cnt := newlyFilledBySubGoal[slot.ID]; cnt > best {
+			best = cnt
+			tracker.active = slot
+		}
+	}
+	if best == 0 && firstChanged != nil {
+		tracker.active = firstChanged
+	}
+	if tracker.active != nil {
+		progress.ActiveSubGoal = tracker.active.ID
+		progress.ActiveDomain = tracker.active.Group
+	}
+	progress.FinalGoalReached = reached
+	tracker.previous = current
+	return progress
+}
+
+// TrackGoal replays all the user turns of the dialogue
+func TrackGoal(dialogue *Dialogue) (*GoalTimeline, error) {
+	tracker, err := NewGoalTracker(dialogue)
+	if err != nil {
+		return nil, err
+	}
+	goal, err := dialogue.ParsedGoal()
+	if err != nil {
+		return nil, err
+	}
+	timeline := &GoalTimeline{
+		DialogueID:         dialogue.DialogueID,
+		Type:               dialogue.Type,
+		SubGoals:           len(goal.SubGoals),
+		FinalGoalReachedAt: -1,
+	}
+	for i, turn := range dialogue.Turns {
+		if turn.Speaker != "usr" {
+			continue
+		}
+		progress := tracker.Replay(i, turn)
+		if progress.FinalGoalReached && timeline.FinalGoalReachedAt == -1 {
+			timeline.FinalGoalReachedAt = i
+		}
+		timeline.Turns = append(timeline.Turns, progress)
+	}
+	return timeline, nil
+}
+
+func goalSlotKey(slot *Slot) string {
+	return strconv.Itoa(slot.ID) + "." + slot.Group + "." + slot.Name
+}
+
+// slotValueString makes single and multi values comparable
+func slotValueString(slot *Slot) string {
+	if slot.Values == nil {
+		return ""
+	}
+	if slot.Values.Single != nil {
+		return *slot.Values.Single
+	}
+	if slot.Values.Multi != nil {
+		b, _ := json.Marshal(*slot.Values.Multi)
+		return string(b)
+	}
+	return ""
+}
@@ -0,0 +1,34 @@
+package crosswoz
+
+import (
+	"testing"
+)
+
+func TestTrackGoal(t *testing.T) {
+	dialogues, err := LoadDialogues("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeline, err := TrackGoal(dialogues[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if timeline.SubGoals != 3 || len(timeline.Turns) != 7 {
+		t.Fatalf("expect 3 sub-goals and 7 user turns, got %d %d", timeline.SubGoals, len(timeline.Turns))
+	}
+	first := timeline.Turns[0]
+	if len(first.NewlyFilled) != 3 || first.ActiveSubGoal != 1 || first.ActiveDomain != "餐馆" {
+		t.Errorf("unexpected progress of the first turn: %+v", first)
+	}
+	second := timeline.Turns[1]
+	if len(second.ValueChanged) != 1 || second.ValueChanged[0] != "1.餐馆.名称" {
+		t.Errorf("expect 餐馆 名称 to be answered, got %v", second.ValueChanged)
+	}
+	if timeline.Turns[3].ActiveSubGoal != 2 || timeline.Turns[5].ActiveSubGoal != 3 {
+		t.Errorf("expect the user to move to sub-goal 2 and 3")
+	}
+	last := timeline.Turns[len(timeline.Turns)-1]
+	if !last.FinalGoalReached || last.FilledSlots != last.TotalSlots || timeline.FinalGoalReachedAt != last.TurnIdx {
+		t.Errorf("expect final goal to be reached only in the last turn: %+v", last)
+	}
+}
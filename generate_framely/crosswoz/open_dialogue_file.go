@@ -0,0 +1,134 @@
+package crosswoz
+
+import (
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+var (
+	zipMagic  = []byte("PK\x03\x04")
+	gzipMagic = []byte{0x1f, 0x8b}
+)
+
+// OpenDialogueFile opens a plain json, .gz or .zip dialogue file, the format is detected from the
+// first bytes of the file so that a wrongly named file is still read correctly.
+// For zip archives the inner json member is read, see pickZipMember.
+func OpenDialogueFile(inputFileFullPath string) (io.ReadCloser, error) {
+	f, err := os.Open(inputFileFullPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open file %s, err: %w", inputFileFullPath, err)
+	}
+	buffered := bufio.NewReader(f)
+	magic, _ := buffered.Peek(4)
+	switch {
+	case bytes.HasPrefix(magic, zipMagic):
+		rc, err := openZipMember(f, inputFileFullPath)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		return rc, nil
+	case bytes.HasPrefix(magic, gzipMagic):
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			f.Close()
+			return nil, fmt.Errorf("failed to read gzip file %s, err: %w", inputFileFullPath, err)
+		}
+		return &multiCloser{Reader: gz, closers: []io.Closer{gz, f}}, nil
+	default:
+		return &multiCloser{Reader: buffered, closers: []io.Closer{f}}, nil
+	}
+}
+
+func openZipMember(f *os.File, inputFileFullPath string) (io.ReadCloser, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	archive, err := zip.NewReader(f, info.Size())
+	if err != nil {
+		return nil, fmt.Errorf("failed to read zip file %s, err: %w", inputFileFullPath, err)
+	}
+	member, err := pickZipMember(archive.File, inputFileFullPath)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := member.Open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in %s, err: %w", member.Name, inputFileFullPath, err)
+	}
+	return &multiCloser{Reader: rc, closers: []io.Closer{rc, f}}, nil
+}
+
+// pickZipMember picks the only json member, or the one named after the archive (test.json in test.json.zip)
+func pickZipMember(files []*zip.File, inputFileFullPath string) (*zip.File, error) {
+	var candidates []*zip.File
+	for _, file := range files {
+		if file.FileInfo().IsDir() || strings.HasPrefix(file.Name, "__MACOSX/") {
+			continue
+		}
+		if strings.HasSuffix(file.Name, ".json") {
+			candidates = append(candidates, file)
+		}
+	}
+	if len(candidates) == 1 {
+		return candidates[0], nil
+	}
+	expected := strings.TrimSuffix(path.Base(inputFileFullPath), ".zip")
+	if !strings.HasSuffix(expected, ".json") {
+		expected += ".json"
+	}
+	for _, file := range candidates {
+		if path.Base(file.Name) == expected {
+			return file, nil
+		}
+	}
+	return nil, fmt.Errorf("can not decide which json member to read in %s, found %d json files", inputFileFullPath, len(candidates))
+}
+
+type multiCloser struct {
+	io.Reader
+	closers []io.Closer
+}
+
+func (mc *multiCloser) Close() error {
+	var firstErr error
+	for _, c := range mc.closers {
+		if err := c.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// DialogueSplitName is the name of a dialogue file without the directory and extensions,
+// data/crosswoz/test.json.zip -> test
+func DialogueSplitName(inputFileFullPath string) string {
+	name := path.Base(inputFileFullPath)
+	for _, ext := range []string{".zip", ".gz", ".json"} {
+		name = strings.TrimSuffix(name, ext)
+	}
+	return name
+}
+
+// ResolveDialogueFile accepts either a path to a dialogue file or a split name like "test",
+// a split name is looked up in dataDir as <name>.json, <name>.json.zip and <name>.json.gz
+func ResolveDialogueFile(dataDir string, nameOrPath string) (string, error) {
+	if info, err := os.Stat(nameOrPath); err == nil && !info.IsDir() {
+		return nameOrPath, nil
+	}
+	for _, ext := range []string{".json", ".json.zip", ".json.gz"} {
+		fileName := path.Join(dataDir, nameOrPath+ext)
+		if _, err := os.Stat(fileName); err == nil {
+			return fileName, nil
+		}
+	}
+	return "", fmt.Errorf("can not find dialogue file %s, neither a path nor a split in %s", nameOrPath, dataDir)
+}
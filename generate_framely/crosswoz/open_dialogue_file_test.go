@@ -0,0 +1,53 @@
+package crosswoz
+
+import (
+	"archive/zip"
+	"compress/gzip"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+)
+
+func TestOpenDialogueFile(t *testing.T) {
+	src := "../../data/crosswoz/demo2303.json"
+	b, err := ioutil.ReadFile(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+
+	gzFile := path.Join(dir, "demo.json.gz")
+	f, _ := os.Create(gzFile)
+	gz := gzip.NewWriter(f)
+	gz.Write(b)
+	gz.Close()
+	f.Close()
+
+	zipFile := path.Join(dir, "demo.json.zip")
+	f, _ = os.Create(zipFile)
+	zw := zip.NewWriter(f)
+	zw.Create("readme.txt")
+	w, _ := zw.Create("demo.json")
+	w.Write(b)
+	zw.Close()
+	f.Close()
+
+	for _, fileName := range []string{src, gzFile, zipFile} {
+		dialogues, err := LoadDialogues(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dialogues) != 1 || dialogues[0].DialogueID != "2303" {
+			t.Errorf("failed to load %s", fileName)
+		}
+	}
+
+	resolved, err := ResolveDialogueFile("../../data/crosswoz", "test")
+	if err != nil || resolved != "../../data/crosswoz/test.json.zip" {
+		t.Errorf("expect test to resolve to test.json.zip, got %s, %v", resolved, err)
+	}
+	if name := DialogueSplitName(resolved); name != "test" {
+		t.Errorf("expect split name test, got %s", name)
+	}
+}
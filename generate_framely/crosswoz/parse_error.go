@@ -0,0 +1,74 @@
+package crosswoz
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ParseError describes where a raw dialogue could not be parsed
+type ParseError struct {
+	File       string // the dialogue file, "" if unknown
+	DialogueID string
+	Section    string // goal, final_goal, sys-usr, user_state, dialog_act, sys_state or sys_state_init
+	MessageIdx int    // -1 if the error is not inside a message
+	SlotIdx    int    // -1 if the error is not inside a slot or dialog act
+	Field      string // id, group, name, value, filled ...
+	Msg        string
+}
+
+// Error reads like "<file> dialog <id> <section> message <i> slot <j> <field>: <msg>", unknown parts are left out
+func (e *ParseError) Error() string {
+	var loc []string
+	if e.File != "" {
+		loc = append(loc, e.File)
+	}
+	loc = append(loc, "dialog "+e.DialogueID)
+	if e.Section != "" {
+		loc = append(loc, e.Section)
+	}
+	if e.MessageIdx >= 0 {
+		loc = append(loc, fmt.Sprintf("message %d", e.MessageIdx))
+	}
+	if e.SlotIdx >= 0 {
+		loc = append(loc, fmt.Sprintf("slot %d", e.SlotIdx))
+	}
+	if e.Field != "" {
+		loc = append(loc, e.Field)
+	}
+	return strings.Join(loc, " ") + ": " + e.Msg
+}
+
+// inFile sets the file of a *ParseError, other errors are returned as they are
+func inFile(err error, file string) error {
+	if parseErr, ok := err.(*ParseError); ok {
+		parseErr.File = file
+	}
+	return err
+}
+
+// ParseReport collects the problems found when loading dialogues leniently,
+// the bad dialogues are skipped instead of aborting the whole file
+type ParseReport struct {
+	Total   int           // dialogues in the file
+	Loaded  int           // dialogues parsed without problems
+	Skipped []string      // sorted ids of the dialogues that were skipped
+	Errors  []*ParseError // every problem found, grouped by dialogue id
+}
+
+func (report *ParseReport) add(dialogID string, errs []*ParseError) {
+	report.Total++
+	if len(errs) == 0 {
+		report.Loaded++
+		return
+	}
+	report.Skipped = append(report.Skipped, dialogID)
+	report.Errors = append(report.Errors, errs...)
+}
+
+func (report *ParseReport) sort() {
+	sort.Strings(report.Skipped)
+	sort.SliceStable(report.Errors, func(i, j int) bool {
+		return report.Errors[i].DialogueID < report.Errors[j].DialogueID
+	})
+}
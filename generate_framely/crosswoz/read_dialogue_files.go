@@ -2,33 +2,94 @@ package crosswoz
 
 import (
 	"encoding/json"
+	"fmt"
 	"io/ioutil"
 	"log"
 	"sort"
 )
 
 func ListRawDialogues(inputFileFullPath string) map[string]*RawDialogue {
-	b, err := ioutil.ReadFile(inputFileFullPath)
+	rawDialogues, err := LoadRawDialogues(inputFileFullPath)
 	if err != nil {
-		log.Fatal("Failed to read file, err:", err)
+		log.Fatal(err)
+	}
+	return rawDialogues
+
+}
+
+// LoadRawDialogues is like ListRawDialogues, but returns the error instead of exiting.
+// The file can be plain json, .gz or .zip, see OpenDialogueFile
+func LoadRawDialogues(inputFileFullPath string) (map[string]*RawDialogue, error) {
+	f, err := OpenDialogueFile(inputFileFullPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	b, err := ioutil.ReadAll(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s, err: %w", inputFileFullPath, err)
 	}
 	var rawDialogues map[string]*RawDialogue
 	if err := json.Unmarshal(b, &rawDialogues); err != nil {
-		log.Fatal("Failed to unmarshal rawDialogues, err:", err)
+		return nil, fmt.Errorf("failed to unmarshal rawDialogues in %s, err: %w", inputFileFullPath, err)
 	}
-	return rawDialogues
-
+	return rawDialogues, nil
 }
 
 func ReadDialogues(inputFileFullPath string) []*Dialogue {
-	rawDialogues := ListRawDialogues(inputFileFullPath)
+	dialogues, err := LoadDialogues(inputFileFullPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return dialogues
+}
+
+// LoadDialogues is like ReadDialogues, but returns the first problem instead of exiting,
+// a problem in a dialogue is always a *ParseError
+func LoadDialogues(inputFileFullPath string) ([]*Dialogue, error) {
+	rawDialogues, err := LoadRawDialogues(inputFileFullPath)
+	if err != nil {
+		return nil, err
+	}
 	var dialogues []*Dialogue
-	for dialogID, rawDialogue := range rawDialogues {
-		dialogue := TransformDialogue(dialogID, rawDialogue)
+	for _, dialogID := range sortedDialogueIDs(rawDialogues) {
+		dialogue, err := ConvertDialogue(dialogID, rawDialogues[dialogID])
+		if err != nil {
+			return nil, inFile(err, inputFileFullPath)
+		}
 		dialogues = append(dialogues, dialogue)
 	}
-	sort.Slice(dialogues, func(i, j int) bool {
-		return dialogues[i].DialogueID < dialogues[j].DialogueID
-	})
-	return dialogues
+	return dialogues, nil
+}
+
+// LoadDialoguesLenient skips the dialogues which can not be parsed and reports all their problems,
+// the error is only for the file which can not be read at all
+func LoadDialoguesLenient(inputFileFullPath string) ([]*Dialogue, *ParseReport, error) {
+	rawDialogues, err := LoadRawDialogues(inputFileFullPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	report := &ParseReport{}
+	var dialogues []*Dialogue
+	for _, dialogID := range sortedDialogueIDs(rawDialogues) {
+		dialogue, errs := convertDialogue(dialogID, rawDialogues[dialogID])
+		for _, e := range errs {
+			e.File = inputFileFullPath
+		}
+		report.add(dialogID, errs)
+		if len(errs) == 0 {
+			dialogues = append(dialogues, dialogue)
+		}
+	}
+	report.sort()
+	return dialogues, report, nil
+}
+
+func sortedDialogueIDs(rawDialogues map[string]*RawDialogue) []string {
+	var dialogIDs []string
+	for dialogID := range rawDialogues {
+		dialogIDs = append(dialogIDs, dialogID)
+	}
+	sort.Strings(dialogIDs)
+	return dialogIDs
 }
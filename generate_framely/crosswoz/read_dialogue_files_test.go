@@ -0,0 +1,80 @@
+package crosswoz
+
+import (
+	"errors"
+	"io/ioutil"
+	"path"
+	"strings"
+	"testing"
+)
+
+const badDialogues = `{
+  "1": {"sys-usr": [1, 2], "goal": [[1, "景点", "名称", "", false]], "final_goal": [],
+        "messages": [{"content": "你好", "role": "usr", "dialog_act": [["General", "greet", "none", "none"]],
+                      "user_state": [[1, "景点", "名称", "", true]]}]},
+  "2": {"sys-usr": [1, 2], "goal": [["x", "景点", "名称", "", false]], "final_goal": [],
+        "messages": [{"content": "你好", "role": "usr", "dialog_act": [["General", "greet"]],
+                      "user_state": [[1, "景点", "名称", 3, true]]}]}
+}`
+
+func TestLoadDialogues(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "bad.json")
+	if err := ioutil.WriteFile(fileName, []byte(badDialogues), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := LoadDialogues(fileName)
+	var parseErr *ParseError
+	if !errors.As(err, &parseErr) {
+		t.Fatalf("expect a parse error, got %v", err)
+	}
+	if parseErr.DialogueID != "2" || parseErr.Section != "goal" || parseErr.Field != "id" {
+		t.Errorf("unexpected parse error: %v", parseErr)
+	}
+	if msg := parseErr.Error(); !strings.HasPrefix(msg, fileName+" dialog 2 goal slot 0 id: ") || strings.Count(msg, " id") != 1 {
+		t.Errorf("unexpected message: %s", msg)
+	}
+
+	dialogues, report, err := LoadDialoguesLenient(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dialogues) != 1 || dialogues[0].DialogueID != "1" {
+		t.Errorf("expect only dialogue 1 to be loaded, got %d dialogues", len(dialogues))
+	}
+	if report.Total != 2 || report.Loaded != 1 || len(report.Skipped) != 1 || len(report.Errors) != 3 {
+		t.Errorf("unexpected report: %+v", report)
+	}
+	for _, e := range report.Errors {
+		t.Log(e)
+	}
+}
+
+func TestBadFinalGoal(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "bad.json")
+	if err := ioutil.WriteFile(fileName, []byte(`{
+  "1": {"sys-usr": [1, 2], "goal": [[1, "景点", "名称", "", false]], "final_goal": [["x", "景点", "名称", "故宫", true]],
+        "messages": []}
+}`), 0666); err != nil {
+		t.Fatal(err)
+	}
+
+	dialogues, report, err := LoadDialoguesLenient(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dialogues) != 0 || len(report.Errors) != 1 ||
+		report.Errors[0].Section != "final_goal" || report.Errors[0].SlotIdx != 0 {
+		t.Errorf("unexpected dialogues %d and report %+v", len(dialogues), report)
+	}
+}
+
+func TestLoadDemoDialogue(t *testing.T) {
+	dialogues, err := LoadDialogues("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dialogues) != 1 || len(dialogues[0].Turns) == 0 {
+		t.Errorf("failed to load demo dialogue")
+	}
+}
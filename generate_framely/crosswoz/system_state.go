@@ -0,0 +1,200 @@
+package crosswoz
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// domains of CrossWOZ, in the order of sys_state
+var Domains = []string{"景点", "餐馆", "酒店", "地铁", "出租"}
+
+const selectedResultsKey = "selectedResults"
+
+// DomainState is the belief state of one domain in sys_state,
+// the slot values are the constraints used to query the database, "" means not constrained
+type DomainState struct {
+	Domain          string
+	Slots           map[string]string
+	SelectedResults []string
+}
+
+// Value of a slot, "" if it is not constrained
+func (ds *DomainState) Value(slot string) string {
+	if ds == nil {
+		return ""
+	}
+	return ds.Slots[slot]
+}
+
+// Constraints returns only the slots with a value
+func (ds *DomainState) Constraints() map[string]string {
+	constraints := make(map[string]string)
+	if ds == nil {
+		return constraints
+	}
+	for slot, value := range ds.Slots {
+		if value != "" {
+			constraints[slot] = value
+		}
+	}
+	return constraints
+}
+
+// SlotNames in sorted order
+func (ds *DomainState) SlotNames() []string {
+	var names []string
+	if ds == nil {
+		return names
+	}
+	for slot := range ds.Slots {
+		names = append(names, slot)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// IsEmpty if the domain is neither constrained nor has selected results
+func (ds *DomainState) IsEmpty() bool {
+	return ds == nil || (len(ds.Constraints()) == 0 && len(ds.SelectedResults) == 0)
+}
+
+func (ds *DomainState) UnmarshalJSON(b []byte) error {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+	ds.Slots = make(map[string]string)
+	for k, v := range raw {
+		if k == selectedResultsKey {
+			if err := json.Unmarshal(v, &ds.SelectedResults); err != nil {
+				return fmt.Errorf("bad %s: %w", selectedResultsKey, err)
+			}
+			continue
+		}
+		value, err := decodeStateValue(v)
+		if err != nil {
+			return fmt.Errorf("bad value of %s: %w", k, err)
+		}
+		ds.Slots[k] = value
+	}
+	return nil
+}
+
+func (ds *DomainState) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]interface{})
+	for k, v := range ds.Slots {
+		raw[k] = v
+	}
+	selectedResults := ds.SelectedResults
+	if selectedResults == nil {
+		selectedResults = []string{}
+	}
+	raw[selectedResultsKey] = selectedResults
+	return json.Marshal(raw)
+}
+
+// decodeStateValue accepts a string, or a list of strings which is joined by spaces like
+// the multi-term conditions of 推荐菜 and 酒店设施
+func decodeStateValue(b []byte) (string, error) {
+	var s string
+	if err := json.Unmarshal(b, &s); err == nil {
+		return s, nil
+	}
+	var values []string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return "", fmt.Errorf("neither string nor list of strings: %s", string(b))
+	}
+	return strings.Join(values, " "), nil
+}
+
+// SystemState is the sys_state of a system message, domain -> domain state
+type SystemState map[string]*DomainState
+
+func (state *SystemState) UnmarshalJSON(b []byte) error {
+	var domains map[string]*DomainState
+	if err := json.Unmarshal(b, &domains); err != nil {
+		return err
+	}
+	for domain, ds := range domains {
+		if ds == nil {
+			ds = &DomainState{Slots: map[string]string{}}
+			domains[domain] = ds
+		}
+		ds.Domain = domain
+	}
+	*state = domains
+	return nil
+}
+
+// decodeSystemState decodes the sys_state or sys_state_init of a message, nil if the message has none
+func decodeSystemState(raw json.RawMessage, dialogID string, section string, msgIdx int) (SystemState, *ParseError) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var state SystemState
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, &ParseError{
+			DialogueID: dialogID,
+			Section:    section,
+			MessageIdx: msgIdx,
+			SlotIdx:    -1,
+			Msg:        err.Error(),
+		}
+	}
+	return state, nil
+}
+
+// Domain returns nil if the domain is not in the state, the accessors of *DomainState accept nil
+func (state SystemState) Domain(domain string) *DomainState {
+	return state[domain]
+}
+
+// Constraint value of domain.slot, "" if not constrained
+func (state SystemState) Constraint(domain string, slot string) string {
+	return state.Domain(domain).Value(slot)
+}
+
+// Constraints of the domain, only the slots with a value
+func (state SystemState) Constraints(domain string) map[string]string {
+	return state.Domain(domain).Constraints()
+}
+
+func (state SystemState) SelectedResults(domain string) []string {
+	if ds := state.Domain(domain); ds != nil {
+		return ds.SelectedResults
+	}
+	return nil
+}
+
+// DomainNames in the order of Domains, unknown domains are appended in sorted order
+func (state SystemState) DomainNames() []string {
+	var names []string
+	known := make(map[string]bool)
+	for _, domain := range Domains {
+		known[domain] = true
+		if _, ok := state[domain]; ok {
+			names = append(names, domain)
+		}
+	}
+	var others []string
+	for domain := range state {
+		if !known[domain] {
+			others = append(others, domain)
+		}
+	}
+	sort.Strings(others)
+	return append(names, others...)
+}
+
+// ActiveDomains are the domains which are constrained or have selected results
+func (state SystemState) ActiveDomains() []string {
+	var active []string
+	for _, domain := range state.DomainNames() {
+		if !state[domain].IsEmpty() {
+			active = append(active, domain)
+		}
+	}
+	return active
+}
@@ -0,0 +1,73 @@
+package crosswoz
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSystemState(t *testing.T) {
+	dialogues, err := LoadDialogues("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	turn := dialogues[0].Turns[1]
+	if turn.Speaker != "sys" || turn.SysState == nil || turn.SysStateInit == nil {
+		t.Fatal("expect sys state in the first sys turn")
+	}
+	if v := turn.SysState.Constraint("餐馆", "人均消费"); v != "50-100元" {
+		t.Errorf("expect 餐馆.人均消费 = 50-100元, got %s", v)
+	}
+	if c := turn.SysState.Constraints("餐馆"); len(c) != 2 || c["推荐菜"] != "美食街" {
+		t.Errorf("unexpected constraints of 餐馆: %v", c)
+	}
+	if r := turn.SysStateInit.SelectedResults("餐馆"); len(r) != 1 || r[0] != "鲜鱼口老字号美食街" {
+		t.Errorf("unexpected selected results of 餐馆: %v", r)
+	}
+	if domains := turn.SysState.ActiveDomains(); !reflect.DeepEqual(domains, []string{"餐馆"}) {
+		t.Errorf("expect only 餐馆 to be active, got %v", domains)
+	}
+	if turn.SysState.Domain("火车").Value("名称") != "" {
+		t.Error("unknown domain should be empty")
+	}
+	if dialogues[0].Turns[0].SysState != nil {
+		t.Error("usr turn should not have sys state")
+	}
+
+	b, err := json.Marshal(turn.SysStateInit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var state SystemState
+	if err := json.Unmarshal(b, &state); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(state, turn.SysStateInit) {
+		t.Errorf("sys state changed after marshal and unmarshal: %s", string(b))
+	}
+}
+
+func TestBadSystemState(t *testing.T) {
+	// a bad sys_state only skips its own dialogue
+	reader := NewDialogueReader(strings.NewReader(`{
+  "1": {"sys-usr": [1, 2], "goal": [], "final_goal": [],
+        "messages": [{"content": "好的", "role": "sys", "dialog_act": [], "sys_state": {"景点": [1, 2]}}]},
+  "2": {"sys-usr": [1, 2], "goal": [], "final_goal": [],
+        "messages": [{"content": "好的", "role": "sys", "dialog_act": [], "sys_state": {"景点": {"名称": "故宫"}}}]}
+}`))
+	var ids []string
+	var parseErrs []*ParseError
+	err := ForEachDialogue(reader, func(err *ParseError) {
+		parseErrs = append(parseErrs, err)
+	}, func(dialogue *Dialogue) {
+		ids = append(ids, dialogue.DialogueID)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ids, []string{"2"}) || len(parseErrs) != 1 ||
+		parseErrs[0].DialogueID != "1" || parseErrs[0].Section != "sys_state" || parseErrs[0].MessageIdx != 0 {
+		t.Errorf("unexpected dialogues %v and errors %v", ids, parseErrs)
+	}
+}
@@ -1,9 +1,10 @@
 package crosswoz
 
 import (
+	"encoding/json"
+	"fmt"
 	"log"
 	"sort"
-	"strconv"
 )
 
 type SelectedResult []interface{}
@@ -15,10 +16,12 @@ type DialogAct struct {
 }
 
 type Message struct {
-	Utterance  string
-	DialogActs []*DialogAct
-	UserState  []*Slot `json:"-"`
-	Speaker    string  // usr or sys
+	Utterance    string
+	DialogActs   []*DialogAct
+	UserState    []*Slot     `json:"-"`
+	SysState     SystemState `json:",omitempty"` // only for sys messages
+	SysStateInit SystemState `json:",omitempty"` // only for sys messages
+	Speaker      string      // usr or sys
 }
 
 type RelatedSlots struct {
@@ -80,8 +83,9 @@ type RawMessage struct {
 	RawDialogAct [][]string      `json:"dialog_act"`
 	Role         string          `json:"role"`
 	UserState    [][]interface{} `json:"user_state"`
-	SysState     map[string]interface{}
-	SysStateInit map[string]interface{}
+	// parsed per dialogue by convertDialogue, a bad state only fails its own dialogue
+	SysState     json.RawMessage `json:"sys_state"`
+	SysStateInit json.RawMessage `json:"sys_state_init"`
 }
 
 type RawDialogue struct {
@@ -108,9 +112,35 @@ type Dialogue struct {
 }
 
 func TransformDialogue(dialogID string, rawDialogue *RawDialogue) *Dialogue {
+	dialogue, errs := convertDialogue(dialogID, rawDialogue)
+	if len(errs) > 0 {
+		log.Fatal(errs[0])
+	}
+	return dialogue
+}
+
+// ConvertDialogue is like TransformDialogue, but returns the first problem found instead of exiting
+func ConvertDialogue(dialogID string, rawDialogue *RawDialogue) (*Dialogue, error) {
+	dialogue, errs := convertDialogue(dialogID, rawDialogue)
+	if len(errs) > 0 {
+		return nil, errs[0]
+	}
+	return dialogue, nil
+}
+
+// convertDialogue goes through the whole raw dialogue and collects all the problems
+func convertDialogue(dialogID string, rawDialogue *RawDialogue) (*Dialogue, []*ParseError) {
+	var errs []*ParseError
+	if rawDialogue == nil {
+		return nil, append(errs, &ParseError{
+			DialogueID: dialogID,
+			MessageIdx: -1,
+			SlotIdx:    -1,
+			Field:      "dialogue",
+			Msg:        "dialogue is null",
+		})
+	}
 	dialogue := &Dialogue{
-		UserVirtualID:   int(rawDialogue.SysUsr[0]),
-		SysVirtualID:    int(rawDialogue.SysUsr[1]),
 		DialogueID:      dialogID,
 		TaskDescription: rawDialogue.TaskDescription,
 		Type:            rawDialogue.Type,
@@ -118,13 +148,36 @@ func TransformDialogue(dialogID string, rawDialogue *RawDialogue) *Dialogue {
 		FinalGoal:       rawDialogue.FinalGoal,
 		Turns:           make([]*Message, len(rawDialogue.Messages)),
 	}
+	if len(rawDialogue.SysUsr) != 2 {
+		errs = append(errs, &ParseError{
+			DialogueID: dialogID,
+			Section:    "sys-usr",
+			MessageIdx: -1,
+			SlotIdx:    -1,
+			Field:      "sys-usr",
+			Msg:        fmt.Sprintf("expect 2 virtual ids, got %v", rawDialogue.SysUsr),
+		})
+	} else {
+		dialogue.UserVirtualID = int(rawDialogue.SysUsr[0])
+		dialogue.SysVirtualID = int(rawDialogue.SysUsr[1])
+	}
 	for i, rawSlot := range rawDialogue.Goal {
-		slot := ParseSlot(rawSlot, dialogID, i)
+		slot, err := decodeSlot(rawSlot, dialogID, "goal", -1, i)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
 		if slot.Filled {
 			log.Printf("!slot in goal should not be filled, dialog: %s, %d 'th slot", dialogID, i)
 		}
 		dialogue.Slots = append(dialogue.Slots, slot)
 	}
+	// the final goal is kept raw, but must parse like the goal
+	for i, rawSlot := range rawDialogue.FinalGoal {
+		if _, err := decodeSlot(rawSlot, dialogID, "final_goal", -1, i); err != nil {
+			errs = append(errs, err)
+		}
+	}
 	// Turns
 	for msgIdx, msg := range rawDialogue.Messages {
 		turn := &Message{
@@ -132,13 +185,35 @@ func TransformDialogue(dialogID string, rawDialogue *RawDialogue) *Dialogue {
 			Utterance: msg.Content,
 		}
 		dialogue.Turns[msgIdx] = turn
+		var err *ParseError
+		if turn.SysState, err = decodeSystemState(msg.SysState, dialogID, "sys_state", msgIdx); err != nil {
+			errs = append(errs, err)
+		}
+		if turn.SysStateInit, err = decodeSystemState(msg.SysStateInit, dialogID, "sys_state_init", msgIdx); err != nil {
+			errs = append(errs, err)
+		}
 		// user state
 		for slotIdx, rawSlot := range msg.UserState {
-			slot := ParseSlot(rawSlot, dialogID+".Messages."+strconv.Itoa(msgIdx), slotIdx)
+			slot, err := decodeSlot(rawSlot, dialogID, "user_state", msgIdx, slotIdx)
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
 			turn.UserState = append(turn.UserState, slot)
 		}
 		// dialog act
-		for _, act := range msg.RawDialogAct {
+		for actIdx, act := range msg.RawDialogAct {
+			if len(act) != 4 {
+				errs = append(errs, &ParseError{
+					DialogueID: dialogID,
+					Section:    "dialog_act",
+					MessageIdx: msgIdx,
+					SlotIdx:    actIdx,
+					Field:      "dialog_act",
+					Msg:        fmt.Sprintf("expect [act, intent, slot, value], got %v", act),
+				})
+				continue
+			}
 			dialogAct := &DialogAct{
 				Act:    act[0],
 				Intent: act[1],
@@ -149,7 +224,7 @@ func TransformDialogue(dialogID string, rawDialogue *RawDialogue) *Dialogue {
 		}
 
 	}
-	return dialogue
+	return dialogue, errs
 }
 
 type SlotValues struct {
@@ -158,9 +233,18 @@ type SlotValues struct {
 }
 
 func (sv *SlotValues) ParseSlotValues(v interface{}) bool {
+	if err := sv.DecodeSlotValues(v); err != nil {
+		log.Println(err)
+		return false
+	}
+	return true
+}
+
+// DecodeSlotValues is like ParseSlotValues, but tells why the value is not good
+func (sv *SlotValues) DecodeSlotValues(v interface{}) error {
 	if s, ok := v.(string); ok {
 		sv.Single = &s
-		return true
+		return nil
 	}
 	if a, ok := v.([]interface{}); ok {
 		var values []string
@@ -168,14 +252,13 @@ func (sv *SlotValues) ParseSlotValues(v interface{}) bool {
 			if s, ok := value.(string); ok {
 				values = append(values, s)
 			} else {
-				log.Println("value not good: ", value)
-				return false
+				return fmt.Errorf("value not good: %v", value)
 			}
 		}
 		sv.Multi = &values
-		return true
+		return nil
 	}
-	return false
+	return fmt.Errorf("value is neither string nor list: %v", v)
 }
 
 type Slot struct {
@@ -183,7 +266,7 @@ type Slot struct {
 	Group  string
 	Name   string
 	Values *SlotValues
-	Filled bool // TODO what's this?
+	Filled bool // the user has informed or requested the slot, see GoalTracker
 }
 
 func (slot *Slot) IsMulti(dialogID string, i int) bool {
@@ -197,39 +280,70 @@ func (slot *Slot) IsMulti(dialogID string, i int) bool {
 	}
 }
 func ParseSlot(rawSlot []interface{}, dialogID string, idx int) *Slot {
+	slot, err := DecodeSlot(rawSlot, dialogID, "", -1, idx)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return slot
+}
+
+// DecodeSlot is like ParseSlot, but returns a *ParseError instead of exiting.
+// msgIdx is -1 for the slots in goal and final_goal
+func DecodeSlot(rawSlot []interface{}, dialogID string, section string, msgIdx int, idx int) (*Slot, error) {
+	slot, err := decodeSlot(rawSlot, dialogID, section, msgIdx, idx)
+	if err != nil {
+		return nil, err
+	}
+	return slot, nil
+}
+
+func decodeSlot(rawSlot []interface{}, dialogID string, section string, msgIdx int, idx int) (*Slot, *ParseError) {
+	fail := func(field string, msg string) *ParseError {
+		return &ParseError{
+			DialogueID: dialogID,
+			Section:    section,
+			MessageIdx: msgIdx,
+			SlotIdx:    idx,
+			Field:      field,
+			Msg:        msg,
+		}
+	}
+	if len(rawSlot) != 5 {
+		return nil, fail("slot", fmt.Sprintf("expect [id, group, name, value, filled], got %v", rawSlot))
+	}
 	slot := &Slot{
 		Values: new(SlotValues),
 	}
 	// id
 	if v, ok := rawSlot[0].(float64); !ok {
-		log.Fatalf("parse id failed, dialog: %s, %d 'th slot: %v", dialogID, idx, rawSlot[0])
+		return nil, fail("id", fmt.Sprintf("not a number: %v", rawSlot[0]))
 	} else {
 		slot.ID = int(v)
 	}
 	// group
 	if v, ok := rawSlot[1].(string); !ok {
-		log.Fatalf("parse group failed, dialog: %s, %d 'th slot", dialogID, idx)
+		return nil, fail("group", fmt.Sprintf("not a string: %v", rawSlot[1]))
 	} else {
 		slot.Group = v
 	}
 	// name
 	if v, ok := rawSlot[2].(string); !ok {
-		log.Fatalf("parse name failed, dialog: %s, %d 'th slot", dialogID, idx)
+		return nil, fail("name", fmt.Sprintf("not a string: %v", rawSlot[2]))
 	} else {
 		slot.Name = v
 	}
 	// value
-	if !slot.Values.ParseSlotValues(rawSlot[3]) {
-		log.Fatalf("parse slot values failed, dialog: %s, %d 'th slot", dialogID, idx)
+	if err := slot.Values.DecodeSlotValues(rawSlot[3]); err != nil {
+		return nil, fail("value", err.Error())
 	}
 
 	// filled
 	if v, ok := rawSlot[4].(bool); !ok {
-		log.Fatalf("parse filled failed, dialog: %s, %d 'th slot", dialogID, idx)
+		return nil, fail("filled", fmt.Sprintf("not a bool: %v", rawSlot[4]))
 	} else {
 		slot.Filled = v
 	}
-	return slot
+	return slot, nil
 }
 
 func MapKeysSorted(m map[string]bool) []string {
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path"
+)
+
+// database file of each domain
+var domainFiles = map[string]string{
+	DomainAttraction: "attraction_db.json",
+	DomainRestaurant: "restaurant_db.json",
+	DomainHotel:      "hotel_db.json",
+	DomainMetro:      "metro_db.json",
+	DomainTaxi:       "taxi_db.json",
+}
+
+// DB is the whole CrossWOZ database in memory
+type DB struct {
+	Attractions   []*Attraction
+	Restaurants   []*Restaurant
+	Hotels        []*Hotel
+	MetroStations []*MetroStation
+	Taxi          *TaxiTemplate
+
+	// domain -> entities in the order of the file
+	entities map[string][]Entity
+	// domain -> name -> entity
+	byName map[string]map[string]Entity
+	// domain -> attributes of the entities, in the same order as entities
+	attributes map[string][]map[string]interface{}
+}
+
+// Load reads all the *_db.json files in dir, like data/crosswoz/database
+func Load(dir string) (*DB, error) {
+	db := &DB{
+		entities:   make(map[string][]Entity),
+		byName:     make(map[string]map[string]Entity),
+		attributes: make(map[string][]map[string]interface{}),
+	}
+	for _, domain := range Domains {
+		fileName := path.Join(dir, domainFiles[domain])
+		if err := db.loadDomain(domain, fileName); err != nil {
+			return nil, err
+		}
+	}
+	return db, nil
+}
+
+func (db *DB) loadDomain(domain string, fileName string) error {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return fmt.Errorf("failed to read %s, err: %w", fileName, err)
+	}
+	var rawEntries [][]json.RawMessage
+	if err := json.Unmarshal(b, &rawEntries); err != nil {
+		return fmt.Errorf("failed to unmarshal %s, err: %w", fileName, err)
+	}
+	db.byName[domain] = make(map[string]Entity)
+	for i, rawEntry := range rawEntries {
+		entity, err := decodeEntry(domain, rawEntry)
+		if err != nil {
+			return fmt.Errorf("invalid %d 'th entry in %s, err: %w", i, fileName, err)
+		}
+		if _, ok := db.byName[domain][entity.EntityName()]; ok {
+			return fmt.Errorf("duplicate entity %s in %s", entity.EntityName(), fileName)
+		}
+		db.byName[domain][entity.EntityName()] = entity
+		db.entities[domain] = append(db.entities[domain], entity)
+		db.attributes[domain] = append(db.attributes[domain], entity.Attributes())
+		switch e := entity.(type) {
+		case *Attraction:
+			db.Attractions = append(db.Attractions, e)
+		case *Restaurant:
+			db.Restaurants = append(db.Restaurants, e)
+		case *Hotel:
+			db.Hotels = append(db.Hotels, e)
+		case *MetroStation:
+			db.MetroStations = append(db.MetroStations, e)
+		case *TaxiTemplate:
+			db.Taxi = e
+		}
+	}
+	return nil
+}
+
+// decodeEntry decodes [name, {"领域": domain, "名称": name, ...}]
+func decodeEntry(domain string, rawEntry []json.RawMessage) (Entity, error) {
+	if len(rawEntry) != 2 {
+		return nil, fmt.Errorf("expect [name, record], got %d elements", len(rawEntry))
+	}
+	var name string
+	if err := json.Unmarshal(rawEntry[0], &name); err != nil {
+		return nil, fmt.Errorf("bad name: %w", err)
+	}
+	var header struct {
+		Domain string  `json:"领域"`
+		Name   *string `json:"名称"`
+	}
+	if err := json.Unmarshal(rawEntry[1], &header); err != nil {
+		return nil, fmt.Errorf("bad record of %s: %w", name, err)
+	}
+	if header.Domain != domain {
+		return nil, fmt.Errorf("domain not agree, %s: %s != %s", name, header.Domain, domain)
+	}
+	if header.Name != nil && *header.Name != name {
+		return nil, fmt.Errorf("name not agree: %s != %s", name, *header.Name)
+	}
+	var entity Entity
+	switch domain {
+	case DomainAttraction:
+		entity = &Attraction{}
+	case DomainRestaurant:
+		entity = &Restaurant{}
+	case DomainHotel:
+		entity = &Hotel{}
+	case DomainMetro:
+		entity = &MetroStation{}
+	case DomainTaxi:
+		entity = &TaxiTemplate{Name: name}
+	default:
+		return nil, fmt.Errorf("unknown domain %s", domain)
+	}
+	if err := json.Unmarshal(rawEntry[1], entity); err != nil {
+		return nil, fmt.Errorf("bad record of %s: %w", name, err)
+	}
+	return entity, nil
+}
+
+// Get the entity by 名称 in the domain
+func (db *DB) Get(domain string, name string) (Entity, bool) {
+	entity, ok := db.byName[domain][name]
+	return entity, ok
+}
+
+// Find the entities by 名称 in all domains, the same place is in 地铁 and one of 景点/餐馆/酒店
+func (db *DB) Find(name string) []Entity {
+	var entities []Entity
+	for _, domain := range Domains {
+		if entity, ok := db.byName[domain][name]; ok {
+			entities = append(entities, entity)
+		}
+	}
+	return entities
+}
+
+// Entities of the domain in the order of the database file
+func (db *DB) Entities(domain string) []Entity {
+	return db.entities[domain]
+}
+
+// Each calls f for every entity of the domain until f returns false
+func (db *DB) Each(domain string, f func(entity Entity) bool) {
+	for _, entity := range db.entities[domain] {
+		if !f(entity) {
+			return
+		}
+	}
+}
+
+func (db *DB) Attraction(name string) *Attraction {
+	if e, ok := db.Get(DomainAttraction, name); ok {
+		return e.(*Attraction)
+	}
+	return nil
+}
+
+func (db *DB) Restaurant(name string) *Restaurant {
+	if e, ok := db.Get(DomainRestaurant, name); ok {
+		return e.(*Restaurant)
+	}
+	return nil
+}
+
+func (db *DB) Hotel(name string) *Hotel {
+	if e, ok := db.Get(DomainHotel, name); ok {
+		return e.(*Hotel)
+	}
+	return nil
+}
+
+func (db *DB) MetroStation(name string) *MetroStation {
+	if e, ok := db.Get(DomainMetro, name); ok {
+		return e.(*MetroStation)
+	}
+	return nil
+}
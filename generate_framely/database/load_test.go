@@ -0,0 +1,42 @@
+package database
+
+import (
+	"testing"
+)
+
+const dbDir = "../../data/crosswoz/database"
+
+func loadTestDB(t *testing.T) *DB {
+	db, err := Load(dbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestLoad(t *testing.T) {
+	db := loadTestDB(t)
+	if len(db.Attractions) != 465 || len(db.Restaurants) != 951 || len(db.Hotels) != 1133 || len(db.MetroStations) != 2549 {
+		t.Errorf("unexpected sizes: %d %d %d %d", len(db.Attractions), len(db.Restaurants), len(db.Hotels), len(db.MetroStations))
+	}
+	if db.Taxi == nil || db.Taxi.CarType != "#CX" || db.Taxi.Plate != "#CP" {
+		t.Errorf("unexpected taxi template: %+v", db.Taxi)
+	}
+
+	gugong := db.Attraction("故宫")
+	if gugong == nil || gugong.Ticket == nil || *gugong.Ticket != 60 || *gugong.Rating != 4.7 || len(gugong.Nearby.Of(DomainRestaurant)) == 0 {
+		t.Errorf("unexpected 故宫: %+v", gugong)
+	}
+	if station := db.MetroStation("八达岭长城"); station == nil || station.Station != nil {
+		t.Errorf("八达岭长城 should have no metro station: %+v", station)
+	}
+	if attrs := gugong.Attributes(); attrs["门票"] != 60 || attrs["名称"] != "故宫" {
+		t.Errorf("unexpected attributes: %v", attrs)
+	}
+	if entities := db.Find("故宫"); len(entities) != 2 {
+		t.Errorf("expect 故宫 in 景点 and 地铁, got %d", len(entities))
+	}
+	if _, ok := db.Get(DomainHotel, "故宫"); ok {
+		t.Error("故宫 is not a hotel")
+	}
+}
@@ -0,0 +1,173 @@
+package database
+
+import (
+	"sort"
+)
+
+// domains with 周边景点/周边餐馆/周边酒店 lists
+var nearbyDomains = []string{DomainAttraction, DomainRestaurant, DomainHotel}
+
+// EntityKey identifies an entity in the database, the same name may be in several domains
+type EntityKey struct {
+	Domain string
+	Name   string
+}
+
+func (key EntityKey) String() string {
+	return key.Domain + "." + key.Name
+}
+
+func keyOf(entity Entity) EntityKey {
+	return EntityKey{Domain: entity.EntityDomain(), Name: entity.EntityName()}
+}
+
+// NearbyIssue is a 周边 reference which breaks the symmetry of database.md
+type NearbyIssue struct {
+	From EntityKey
+	To   EntityKey
+	Kind string // NearbyAsymmetric or NearbyDangling
+}
+
+// kinds of nearby issues
+const (
+	// From lists To, but To does not list From
+	NearbyAsymmetric = "asymmetric"
+	// From lists To, but there is no such entity
+	NearbyDangling = "dangling"
+)
+
+// NearbyReport of the validation, the issues are sorted by From, To
+type NearbyReport struct {
+	Entities   int
+	Edges      int
+	Asymmetric []*NearbyIssue
+	Dangling   []*NearbyIssue
+}
+
+// NearbyGraph is the directed graph of the 周边 lists of 景点, 餐馆 and 酒店.
+// The edges are kept as listed, so that the asymmetric ones can be found by Validate.
+type NearbyGraph struct {
+	db    *DB
+	edges map[EntityKey][]EntityKey
+	// dangling references, entity -> names listed but not found
+	dangling map[EntityKey][]EntityKey
+}
+
+// NewNearbyGraph indexes the 周边 lists of the database
+func NewNearbyGraph(db *DB) *NearbyGraph {
+	graph := &NearbyGraph{
+		db:       db,
+		edges:    make(map[EntityKey][]EntityKey),
+		dangling: make(map[EntityKey][]EntityKey),
+	}
+	for _, domain := range nearbyDomains {
+		for _, entity := range db.Entities(domain) {
+			from := keyOf(entity)
+			nearby := nearbyOf(entity)
+			for _, toDomain := range nearbyDomains {
+				for _, name := range nearby.Of(toDomain) {
+					to := EntityKey{Domain: toDomain, Name: name}
+					if _, ok := db.Get(toDomain, name); !ok {
+						graph.dangling[from] = append(graph.dangling[from], to)
+						continue
+					}
+					graph.edges[from] = append(graph.edges[from], to)
+				}
+			}
+		}
+	}
+	return graph
+}
+
+func nearbyOf(entity Entity) *Nearby {
+	switch e := entity.(type) {
+	case *Attraction:
+		return &e.Nearby
+	case *Restaurant:
+		return &e.Nearby
+	case *Hotel:
+		return &e.Nearby
+	}
+	return &Nearby{}
+}
+
+// Neighbors of the entity in the domain, in the order of the 周边 list, nil if the entity is not found
+func (graph *NearbyGraph) Neighbors(domain string, name string, nearbyDomain string) []Entity {
+	var neighbors []Entity
+	for _, to := range graph.edges[EntityKey{Domain: domain, Name: name}] {
+		if to.Domain != nearbyDomain {
+			continue
+		}
+		entity, _ := graph.db.Get(to.Domain, to.Name)
+		neighbors = append(neighbors, entity)
+	}
+	return neighbors
+}
+
+// IsNeighbor if the 周边 list of from contains to
+func (graph *NearbyGraph) IsNeighbor(from EntityKey, to EntityKey) bool {
+	for _, key := range graph.edges[from] {
+		if key == to {
+			return true
+		}
+	}
+	return false
+}
+
+// Reachable finds the entities within k hops from the entity, as entity -> number of hops.
+// The entity itself is at 0 hop.
+func (graph *NearbyGraph) Reachable(domain string, name string, k int) map[EntityKey]int {
+	start := EntityKey{Domain: domain, Name: name}
+	if _, ok := graph.db.Get(domain, name); !ok {
+		return nil
+	}
+	hops := map[EntityKey]int{start: 0}
+	frontier := []EntityKey{start}
+	for hop := 1; hop <= k && len(frontier) > 0; hop++ {
+		var next []EntityKey
+		for _, from := range frontier {
+			for _, to := range graph.edges[from] {
+				if _, ok := hops[to]; ok {
+					continue
+				}
+				hops[to] = hop
+				next = append(next, to)
+			}
+		}
+		frontier = next
+	}
+	return hops
+}
+
+// Validate finds the asymmetric and dangling 周边 references
+func (graph *NearbyGraph) Validate() *NearbyReport {
+	report := &NearbyReport{}
+	for _, domain := range nearbyDomains {
+		report.Entities += len(graph.db.Entities(domain))
+	}
+	for from, edges := range graph.edges {
+		report.Edges += len(edges)
+		for _, to := range edges {
+			if !graph.IsNeighbor(to, from) {
+				report.Asymmetric = append(report.Asymmetric, &NearbyIssue{From: from, To: to, Kind: NearbyAsymmetric})
+			}
+		}
+	}
+	for from, edges := range graph.dangling {
+		for _, to := range edges {
+			report.Dangling = append(report.Dangling, &NearbyIssue{From: from, To: to, Kind: NearbyDangling})
+		}
+	}
+	sortNearbyIssues(report.Asymmetric)
+	sortNearbyIssues(report.Dangling)
+	return report
+}
+
+func sortNearbyIssues(issues []*NearbyIssue) {
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].From != issues[j].From {
+			return issues[i].From.String() < issues[j].From.String()
+		}
+		return issues[i].To.String() < issues[j].To.String()
+	})
+}
@@ -0,0 +1,47 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestNearbyGraph(t *testing.T) {
+	db := loadTestDB(t)
+	graph := NewNearbyGraph(db)
+	report := graph.Validate()
+	if report.Edges == 0 || len(report.Asymmetric) != 0 || len(report.Dangling) != 0 {
+		t.Errorf("expect the 周边 lists to be symmetric: %d edges, %d asymmetric, %d dangling",
+			report.Edges, len(report.Asymmetric), len(report.Dangling))
+	}
+
+	neighbors := graph.Neighbors(DomainHotel, "北京丽晶酒店", DomainRestaurant)
+	if len(neighbors) != len(db.Hotel("北京丽晶酒店").Restaurants) || neighbors[0].EntityDomain() != DomainRestaurant {
+		t.Errorf("unexpected 周边餐馆 of 北京丽晶酒店: %v", neighbors)
+	}
+	if !graph.IsNeighbor(EntityKey{DomainRestaurant, neighbors[0].EntityName()}, EntityKey{DomainHotel, "北京丽晶酒店"}) {
+		t.Error("expect the 周边 relation to be symmetric")
+	}
+
+	hops := graph.Reachable(DomainAttraction, "故宫", 2)
+	if hops[EntityKey{DomainAttraction, "故宫"}] != 0 || len(hops) <= len(graph.Reachable(DomainAttraction, "故宫", 1)) {
+		t.Errorf("unexpected reachable entities of 故宫: %d", len(hops))
+	}
+	for key, hop := range graph.Reachable(DomainAttraction, "故宫", 1) {
+		if hop == 1 && !graph.IsNeighbor(EntityKey{DomainAttraction, "故宫"}, key) {
+			t.Errorf("%s is not a neighbor of 故宫", key)
+		}
+	}
+	if graph.Reachable(DomainAttraction, "不存在的景点", 1) != nil {
+		t.Error("expect nil for an unknown entity")
+	}
+
+	// break the symmetry
+	hotel := db.Hotel("北京丽晶酒店")
+	hotel.Restaurants = append(hotel.Restaurants[1:], "不存在的餐馆")
+	report = NewNearbyGraph(db).Validate()
+	if len(report.Asymmetric) != 1 || report.Asymmetric[0].To.Name != "北京丽晶酒店" {
+		t.Errorf("expect one asymmetric reference: %+v", report.Asymmetric)
+	}
+	if len(report.Dangling) != 1 || report.Dangling[0].To != (EntityKey{DomainRestaurant, "不存在的餐馆"}) {
+		t.Errorf("expect one dangling reference: %+v", report.Dangling)
+	}
+}
@@ -0,0 +1,317 @@
+package database
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Query semantics follow convlab2/util/crosswoz/dbquery.py and database.md:
+//   - 名称 is matched by substring and overrides all the other constraints
+//   - 门票/评分/人均消费/价格 are range queries, "<x" | ">x" | "x-y" | "x", the bounds are inclusive
+//   - 周边XX matches if any of the list contains the value
+//   - 推荐菜/酒店设施 accept multiple terms separated by spaces, every term should match
+//   - other slots are matched by substring
+//   - 地铁/出租 are queried by 出发地 and 目的地
+
+// kinds of constraints
+const (
+	MatchSubstring = "substring"
+	MatchRange     = "range"
+	MatchInList    = "in"
+	MatchMultiple  = "multiple"
+)
+
+var rangeSlots = map[string]bool{"门票": true, "评分": true, "人均消费": true, "价格": true}
+var multipleSlots = map[string]bool{"推荐菜": true, "酒店设施": true}
+
+// queryable slots of each domain, marked by * in database.md
+var querySlots = map[string]map[string]bool{
+	DomainAttraction: {"名称": true, "门票": true, "游玩时间": true, "评分": true, "周边景点": true, "周边餐馆": true, "周边酒店": true},
+	DomainRestaurant: {"名称": true, "推荐菜": true, "人均消费": true, "评分": true, "周边景点": true, "周边餐馆": true, "周边酒店": true},
+	DomainHotel:      {"名称": true, "酒店类型": true, "酒店设施": true, "价格": true, "评分": true, "周边景点": true, "周边餐馆": true, "周边酒店": true},
+	DomainMetro:      {"出发地": true, "目的地": true},
+	DomainTaxi:       {"出发地": true, "目的地": true},
+}
+
+// Range is inclusive on both ends, nil means unbounded
+type Range struct {
+	Low  *float64
+	High *float64
+}
+
+func (r *Range) Contains(v float64) bool {
+	return (r.Low == nil || v >= *r.Low) && (r.High == nil || v <= *r.High)
+}
+
+func (r *Range) String() string {
+	switch {
+	case r.Low != nil && r.High != nil && *r.Low == *r.High:
+		return formatNumber(*r.Low)
+	case r.Low != nil && r.High != nil:
+		return formatNumber(*r.Low) + "-" + formatNumber(*r.High)
+	case r.Low != nil:
+		return ">" + formatNumber(*r.Low)
+	case r.High != nil:
+		return "<" + formatNumber(*r.High)
+	}
+	return ""
+}
+
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+var (
+	regRangeGrammar = regexp.MustCompile(`^([<>]?)(\d+(?:\.\d+)?)(?:-(\d+(?:\.\d+)?))?$`)
+	regNumberRange  = regexp.MustCompile(`^(\d+(?:\.\d+)?)[^\d.]*[-~到至](\d+(?:\.\d+)?)`)
+	regNumber       = regexp.MustCompile(`^(\d+(?:\.\d+)?)`)
+)
+
+// ParseRange parses the constraint grammar of database.md: "<x", ">x", "x-y" or "x"
+func ParseRange(value string) (*Range, error) {
+	m := regRangeGrammar.FindStringSubmatch(strings.TrimSpace(value))
+	if m == nil {
+		return nil, fmt.Errorf("not a range: %s", value)
+	}
+	first, _ := strconv.ParseFloat(m[2], 64)
+	switch {
+	case m[1] == ">" && m[3] == "":
+		return &Range{Low: &first}, nil
+	case m[1] == "<" && m[3] == "":
+		return &Range{High: &first}, nil
+	case m[1] == "" && m[3] == "":
+		return &Range{Low: &first, High: &first}, nil
+	case m[1] == "":
+		second, _ := strconv.ParseFloat(m[3], 64)
+		return &Range{Low: &first, High: &second}, nil
+	}
+	return nil, fmt.Errorf("not a range: %s", value)
+}
+
+// NormalizeConstraint rewrites the phrasings of goals and sys_state into the constraint grammar,
+// like "4.5分以上" -> ">4.5", "100-150元" -> "100-150", "免费" -> "0", "50元以下" -> "<50".
+// As in dbquery.py, a single 评分 means the lowest acceptable score.
+// Values of slots which are not range queries are only trimmed.
+func NormalizeConstraint(slot string, value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if !rangeSlots[slot] {
+		return value, nil
+	}
+	// a bare score like 4.5 is a lower bound too, not an exact match
+	if slot == "评分" && value != "" && regNumber.FindString(value) == value {
+		return ">" + value, nil
+	}
+	if _, err := ParseRange(value); err == nil {
+		return value, nil
+	}
+	if slot == "门票" {
+		switch value {
+		case "免费", "免票", "不要钱", "不花钱":
+			return "0", nil
+		case "不免费", "不免票", "收费":
+			return ">1", nil
+		}
+	}
+	if m := regNumberRange.FindStringSubmatch(value); m != nil {
+		return m[1] + "-" + m[2], nil
+	}
+	m := regNumber.FindStringSubmatch(value)
+	if m == nil {
+		return "", fmt.Errorf("can not normalize %s: %s", slot, value)
+	}
+	switch {
+	case strings.Contains(value, "以上") || strings.Contains(value, "起") || slot == "评分":
+		return ">" + m[1], nil
+	case strings.Contains(value, "以下") || strings.Contains(value, "以内"):
+		return "<" + m[1], nil
+	}
+	return m[1], nil
+}
+
+// Constraint is a parsed condition on a slot
+type Constraint struct {
+	Slot  string
+	Kind  string // MatchSubstring, MatchRange, MatchInList or MatchMultiple
+	Value string // normalized value
+	Range *Range
+	Terms []string // for MatchMultiple
+}
+
+// ParseConstraints parses the non-empty constraints of a domain, like the slots of sys_state
+func ParseConstraints(domain string, constraints map[string]string) ([]*Constraint, error) {
+	slots, ok := querySlots[domain]
+	if !ok {
+		return nil, fmt.Errorf("unknown domain %s", domain)
+	}
+	var parsed []*Constraint
+	for slot, value := range constraints {
+		if strings.TrimSpace(value) == "" {
+			continue
+		}
+		if !slots[slot] {
+			return nil, fmt.Errorf("can not query %s by %s", domain, slot)
+		}
+		normalized, err := NormalizeConstraint(slot, value)
+		if err != nil {
+			return nil, err
+		}
+		c := &Constraint{Slot: slot, Value: normalized, Kind: MatchSubstring}
+		switch {
+		case rangeSlots[slot]:
+			c.Kind = MatchRange
+			c.Range, _ = ParseRange(normalized)
+		case multipleSlots[slot]:
+			c.Kind = MatchMultiple
+			c.Terms = strings.Fields(normalized)
+		case strings.HasPrefix(slot, "周边"):
+			c.Kind = MatchInList
+		}
+		parsed = append(parsed, c)
+	}
+	sort.Slice(parsed, func(i, j int) bool {
+		return parsed[i].Slot < parsed[j].Slot
+	})
+	return parsed, nil
+}
+
+// Match tells if the entity satisfies the constraint, a missing value never matches
+func (c *Constraint) Match(entity Entity) bool {
+	return c.matchAttributes(entity.Attributes())
+}
+
+func (c *Constraint) matchAttributes(attrs map[string]interface{}) bool {
+	value := attrs[c.Slot]
+	if value == nil {
+		return false
+	}
+	switch c.Kind {
+	case MatchRange:
+		v, ok := toFloat(value)
+		return ok && c.Range.Contains(v)
+	case MatchInList:
+		return listContains(value, c.Value)
+	case MatchMultiple:
+		for _, term := range c.Terms {
+			if !listContains(value, term) {
+				return false
+			}
+		}
+		return true
+	}
+	s, ok := value.(string)
+	return ok && strings.Contains(s, c.Value)
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// listContains is true if any of the list contains s, like contains() of dbquery.py
+func listContains(v interface{}, s string) bool {
+	list, ok := v.([]string)
+	if !ok {
+		return false
+	}
+	for _, item := range list {
+		if strings.Contains(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// Query finds the entities of the domain satisfying all the constraints.
+// The matches are ranked by exact 名称 first, then by 评分 from high to low, then in the database order.
+// For 地铁 the results are the stations of 出发地 and 目的地, for 出租 the template if both are given.
+func (db *DB) Query(domain string, constraints map[string]string) ([]Entity, error) {
+	parsed, err := ParseConstraints(domain, constraints)
+	if err != nil {
+		return nil, err
+	}
+	switch domain {
+	case DomainMetro:
+		return db.queryMetro(constraints["出发地"], constraints["目的地"]), nil
+	case DomainTaxi:
+		if constraints["出发地"] == "" || constraints["目的地"] == "" {
+			return nil, nil
+		}
+		return []Entity{db.Taxi}, nil
+	}
+	for _, c := range parsed {
+		if c.Slot == "名称" {
+			// 名称 overrides all the other constraints
+			parsed = []*Constraint{c}
+			break
+		}
+	}
+	var results []Entity
+	for i, entity := range db.entities[domain] {
+		attrs := db.attributes[domain][i]
+		matched := true
+		for _, c := range parsed {
+			if !c.matchAttributes(attrs) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, entity)
+		}
+	}
+	name := strings.TrimSpace(constraints["名称"])
+	sort.SliceStable(results, func(i, j int) bool {
+		iExact, jExact := results[i].EntityName() == name, results[j].EntityName() == name
+		if iExact != jExact {
+			return iExact
+		}
+		return rating(results[i]) > rating(results[j])
+	})
+	return results, nil
+}
+
+func rating(entity Entity) float64 {
+	var r *float64
+	switch e := entity.(type) {
+	case *Attraction:
+		r = e.Rating
+	case *Restaurant:
+		r = e.Rating
+	case *Hotel:
+		r = &e.Rating
+	}
+	if r == nil {
+		return math.Inf(-1)
+	}
+	return *r
+}
+
+// queryMetro finds the stations of the start and the end, an exact name is preferred to a substring
+func (db *DB) queryMetro(start string, end string) []Entity {
+	var results []Entity
+	for _, place := range []string{strings.TrimSpace(start), strings.TrimSpace(end)} {
+		if place == "" {
+			continue
+		}
+		if station, ok := db.Get(DomainMetro, place); ok {
+			results = append(results, station)
+			continue
+		}
+		for _, station := range db.entities[DomainMetro] {
+			if strings.Contains(station.EntityName(), place) {
+				results = append(results, station)
+				break
+			}
+		}
+	}
+	return results
+}
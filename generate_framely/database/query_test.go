@@ -0,0 +1,86 @@
+package database
+
+import (
+	"testing"
+)
+
+func TestNormalizeConstraint(t *testing.T) {
+	cases := []struct {
+		slot, value, expected string
+	}{
+		{"评分", "4.5分以上", ">4.5"},
+		{"评分", "4分", ">4"},
+		{"评分", "4.5", ">4.5"},
+		{"评分", ">4", ">4"},
+		{"人均消费", "100-150元", "100-150"},
+		{"价格", "500元以上", ">500"},
+		{"门票", "50元以下", "<50"},
+		{"门票", "免费", "0"},
+		{"门票", "不免费", ">1"},
+		{"门票", "20元到50元", "20-50"},
+		{"价格", "<300", "<300"},
+		{"酒店类型", " 经济型 ", "经济型"},
+	}
+	for _, c := range cases {
+		if normalized, err := NormalizeConstraint(c.slot, c.value); err != nil || normalized != c.expected {
+			t.Errorf("%s %s: expect %s, got %s, %v", c.slot, c.value, c.expected, normalized, err)
+		}
+	}
+	if _, err := NormalizeConstraint("评分", "很高"); err == nil {
+		t.Error("expect error for value without number")
+	}
+}
+
+func TestQuery(t *testing.T) {
+	db := loadTestDB(t)
+
+	results, err := db.Query(DomainRestaurant, map[string]string{"推荐菜": "美食街", "人均消费": "50-100元", "评分": ""})
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, r := range results {
+		restaurant := r.(*Restaurant)
+		if restaurant.PricePerPerson < 50 || restaurant.PricePerPerson > 100 || !listContains(restaurant.Dishes, "美食街") {
+			t.Errorf("%s does not satisfy the constraints", restaurant.Name)
+		}
+		found = found || restaurant.Name == "鲜鱼口老字号美食街"
+	}
+	if !found {
+		t.Error("expect 鲜鱼口老字号美食街 in the results")
+	}
+	for i := 1; i < len(results); i++ {
+		if rating(results[i-1]) < rating(results[i]) {
+			t.Error("results should be ranked by 评分")
+		}
+	}
+
+	results, _ = db.Query(DomainHotel, map[string]string{"酒店设施": "吹风机 健身房", "评分": "4.5分以上", "价格": "<500"})
+	for _, r := range results {
+		hotel := r.(*Hotel)
+		if !hotel.HasFacility("吹风机") || !hotel.HasFacility("健身房") || hotel.Rating < 4.5 || hotel.Price == nil || *hotel.Price > 500 {
+			t.Errorf("%s does not satisfy the constraints", hotel.Name)
+		}
+	}
+
+	results, _ = db.Query(DomainAttraction, map[string]string{"名称": "故宫", "门票": "免费"})
+	if len(results) == 0 || results[0].EntityName() != "故宫" {
+		t.Error("名称 should override other constraints and the exact name should come first")
+	}
+
+	results, _ = db.Query(DomainAttraction, map[string]string{"周边餐馆": "鲜鱼口老字号美食街", "评分": "4.5分以上"})
+	if len(results) == 0 {
+		t.Error("expect attractions near 鲜鱼口老字号美食街")
+	}
+
+	results, _ = db.Query(DomainMetro, map[string]string{"出发地": "故宫", "目的地": "颐和园"})
+	if len(results) != 2 || *results[1].(*MetroStation).Station != "中关村地铁站E口" {
+		t.Errorf("unexpected metro results: %v", results)
+	}
+	if results, _ := db.Query(DomainTaxi, map[string]string{"出发地": "故宫"}); len(results) != 0 {
+		t.Error("taxi needs both 出发地 and 目的地")
+	}
+	if _, err := db.Query(DomainAttraction, map[string]string{"电话": "010"}); err == nil {
+		t.Error("电话 is not queryable")
+	}
+}
@@ -0,0 +1,126 @@
+package database
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// placeholders of 出租 in taxi_db.json and in the sys utterances
+const (
+	TaxiCarTypePlaceholder = "#CX"
+	TaxiPlatePlaceholder   = "#CP"
+)
+
+// MetroStationOf resolves a place of any domain to its nearest metro station.
+// The 地铁 domain is tried first, then the 地铁 field of 景点/餐馆/酒店.
+// ok is false if the place is unknown, station is empty if the place has no metro station.
+func (db *DB) MetroStationOf(place string) (station string, ok bool) {
+	place = strings.TrimSpace(place)
+	if place == "" {
+		return "", false
+	}
+	// exact names only, the substring fallback of queryMetro is for Query
+	if entity, ok := db.Get(DomainMetro, place); ok {
+		if s := entity.(*MetroStation).Station; s != nil {
+			return *s, true
+		}
+		return "", true
+	}
+	for _, entity := range db.Find(place) {
+		if s, ok := entity.Attributes()["地铁"].(string); ok {
+			return s, true
+		}
+	}
+	return "", false
+}
+
+// MetroAnswer answers 出发地附近地铁站 and 目的地附近地铁站 of a 地铁 query
+type MetroAnswer struct {
+	From        string
+	To          string
+	FromStation string // empty if 出发地 has no metro station
+	ToStation   string // empty if 目的地 has no metro station
+}
+
+// AnswerMetro resolves the metro stations of 出发地 and 目的地, both should be known places
+func (db *DB) AnswerMetro(from string, to string) (*MetroAnswer, error) {
+	answer := &MetroAnswer{From: from, To: to}
+	var ok bool
+	if answer.FromStation, ok = db.MetroStationOf(from); !ok {
+		return nil, fmt.Errorf("unknown 出发地 %s", from)
+	}
+	if answer.ToStation, ok = db.MetroStationOf(to); !ok {
+		return nil, fmt.Errorf("unknown 目的地 %s", to)
+	}
+	return answer, nil
+}
+
+// SlotValues of the 地铁 intent, a missing station is informed as "无" like in the dialogues
+func (answer *MetroAnswer) SlotValues() map[string]string {
+	return map[string]string{
+		"出发地":      answer.From,
+		"目的地":      answer.To,
+		"出发地附近地铁站": stationOrNone(answer.FromStation),
+		"目的地附近地铁站": stationOrNone(answer.ToStation),
+	}
+}
+
+func stationOrNone(station string) string {
+	if station == "" {
+		return "无"
+	}
+	return station
+}
+
+var (
+	fakeCarColors = []string{"黑色", "白色", "银色", "红色", "蓝色", "灰色"}
+	fakeCarBrands = []string{"大众", "丰田", "本田", "别克", "现代", "日产", "比亚迪", "奥迪"}
+	// letters of Beijing plates, I and O are not used
+	fakePlateLetters = "ABCDEFGHJKLMNPQ"
+	fakePlateChars   = "0123456789ABCDEFGHJKLMNPQRSTUVWXYZ"
+)
+
+// Taxi is a booked taxi with a fake 车型 and 车牌
+type Taxi struct {
+	From    string
+	To      string
+	CarType string // like 黑色大众
+	Plate   string // like 京N8K2Q7
+}
+
+// FakeTaxi fills the placeholders of the taxi template, the same 出发地 and 目的地 always get the same taxi
+func FakeTaxi(from string, to string) *Taxi {
+	h := fnv.New64a()
+	h.Write([]byte(from + "\x00" + to))
+	n := h.Sum64()
+	next := func(size int) int {
+		v := int(n % uint64(size))
+		n /= uint64(size)
+		return v
+	}
+	taxi := &Taxi{From: from, To: to}
+	taxi.CarType = fakeCarColors[next(len(fakeCarColors))] + fakeCarBrands[next(len(fakeCarBrands))]
+	plate := []byte("京")
+	plate = append(plate, fakePlateLetters[next(len(fakePlateLetters))])
+	for i := 0; i < 5; i++ {
+		plate = append(plate, fakePlateChars[next(len(fakePlateChars))])
+	}
+	taxi.Plate = string(plate)
+	return taxi
+}
+
+// SlotValues of the 出租 intent
+func (taxi *Taxi) SlotValues() map[string]string {
+	return map[string]string{
+		"出发地": taxi.From,
+		"目的地": taxi.To,
+		"车型":  taxi.CarType,
+		"车牌":  taxi.Plate,
+	}
+}
+
+// Fill replaces #CX and #CP in a sys utterance or an informed value
+func (taxi *Taxi) Fill(s string) string {
+	return strings.NewReplacer(TaxiCarTypePlaceholder, taxi.CarType, TaxiPlatePlaceholder, taxi.Plate).Replace(s)
+}
@@ -0,0 +1,52 @@
+package database
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestMetroStationOf(t *testing.T) {
+	db := loadTestDB(t)
+	if station, ok := db.MetroStationOf("故宫"); !ok || station != "灯市口地铁站A口" {
+		t.Errorf("unexpected station of 故宫: %s %v", station, ok)
+	}
+	if station, ok := db.MetroStationOf("八达岭长城"); !ok || station != "" {
+		t.Errorf("expect no station of 八达岭长城: %s %v", station, ok)
+	}
+	// fragments of names are unknown places
+	for _, place := range []string{"不存在的地方", "北京", "饭店", "公园"} {
+		if station, ok := db.MetroStationOf(place); ok {
+			t.Errorf("expect an unknown place %s, got %s", place, station)
+		}
+	}
+
+	answer, err := db.AnswerMetro("7天连锁酒店(北京天坛东门地铁站店)", "八达岭长城")
+	if err != nil {
+		t.Fatal(err)
+	}
+	values := answer.SlotValues()
+	if values["出发地附近地铁站"] != "天坛东门地铁站B口" || values["目的地附近地铁站"] != "无" {
+		t.Errorf("unexpected answer: %v", values)
+	}
+	if _, err := db.AnswerMetro("故宫", "不存在的地方"); err == nil {
+		t.Error("expect an error for an unknown 目的地")
+	}
+}
+
+func TestFakeTaxi(t *testing.T) {
+	taxi := FakeTaxi("鬼味烤翅", "北京华尔顿酒店(原鸿坤国际大酒店)")
+	if again := FakeTaxi("鬼味烤翅", "北京华尔顿酒店(原鸿坤国际大酒店)"); *again != *taxi {
+		t.Errorf("expect the same taxi: %+v %+v", taxi, again)
+	}
+	if other := FakeTaxi("北京华尔顿酒店(原鸿坤国际大酒店)", "鬼味烤翅"); other.Plate == taxi.Plate {
+		t.Errorf("expect another plate for the way back: %s", other.Plate)
+	}
+	if !regexp.MustCompile(`^京[A-Z][0-9A-Z]{5}$`).MatchString(taxi.Plate) || taxi.CarType == "" {
+		t.Errorf("unexpected taxi: %+v", taxi)
+	}
+	filled := taxi.Fill("车型是#CX，车牌号#CP，您记好。")
+	if strings.Contains(filled, "#") || !strings.Contains(filled, taxi.Plate) || !strings.Contains(filled, taxi.CarType) {
+		t.Errorf("unexpected filled utterance: %s", filled)
+	}
+}
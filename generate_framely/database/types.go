@@ -0,0 +1,196 @@
+package database
+
+// Typed records of data/crosswoz/database/*_db.json, see database.md there.
+// Missing values are null in the json files, the nullable fields are pointers.
+
+const (
+	DomainAttraction = "景点"
+	DomainRestaurant = "餐馆"
+	DomainHotel      = "酒店"
+	DomainMetro      = "地铁"
+	DomainTaxi       = "出租"
+)
+
+// Domains in the order of the database files
+var Domains = []string{DomainAttraction, DomainRestaurant, DomainHotel, DomainMetro, DomainTaxi}
+
+// Entity is a record of any domain
+type Entity interface {
+	EntityName() string
+	EntityDomain() string
+	// Attributes are the attribute name -> value, the value is a string, int, float64, []string or nil if missing
+	Attributes() map[string]interface{}
+}
+
+// Nearby are the 周边 lists, they are symmetric: A is near B iff B is near A
+type Nearby struct {
+	Attractions []string `json:"周边景点"`
+	Restaurants []string `json:"周边餐馆"`
+	Hotels      []string `json:"周边酒店"`
+}
+
+// Of returns the nearby entities of the domain, nil for domains without 周边 lists
+func (nearby *Nearby) Of(domain string) []string {
+	switch domain {
+	case DomainAttraction:
+		return nearby.Attractions
+	case DomainRestaurant:
+		return nearby.Restaurants
+	case DomainHotel:
+		return nearby.Hotels
+	}
+	return nil
+}
+
+func (nearby *Nearby) addAttributes(attrs map[string]interface{}) {
+	attrs["周边景点"] = nearby.Attractions
+	attrs["周边餐馆"] = nearby.Restaurants
+	attrs["周边酒店"] = nearby.Hotels
+}
+
+type Attraction struct {
+	Name     string   `json:"名称"`
+	Address  string   `json:"地址"`
+	Metro    *string  `json:"地铁"` // missing for some attractions
+	Phone    string   `json:"电话"`
+	Ticket   *int     `json:"门票"`
+	PlayTime string   `json:"游玩时间"`
+	Rating   *float64 `json:"评分"`
+	Nearby
+}
+
+func (a *Attraction) EntityName() string   { return a.Name }
+func (a *Attraction) EntityDomain() string { return DomainAttraction }
+func (a *Attraction) Attributes() map[string]interface{} {
+	attrs := map[string]interface{}{
+		"名称":   a.Name,
+		"地址":   a.Address,
+		"地铁":   stringOrNil(a.Metro),
+		"电话":   a.Phone,
+		"门票":   intOrNil(a.Ticket),
+		"游玩时间": a.PlayTime,
+		"评分":   floatOrNil(a.Rating),
+	}
+	a.addAttributes(attrs)
+	return attrs
+}
+
+type Restaurant struct {
+	Name           string   `json:"名称"`
+	Address        string   `json:"地址"`
+	Metro          *string  `json:"地铁"` // missing for some restaurants
+	Phone          string   `json:"电话"`
+	OpeningHours   string   `json:"营业时间"`
+	Dishes         []string `json:"推荐菜"`
+	PricePerPerson int      `json:"人均消费"`
+	Rating         *float64 `json:"评分"`
+	Nearby
+}
+
+func (r *Restaurant) EntityName() string   { return r.Name }
+func (r *Restaurant) EntityDomain() string { return DomainRestaurant }
+func (r *Restaurant) Attributes() map[string]interface{} {
+	attrs := map[string]interface{}{
+		"名称":   r.Name,
+		"地址":   r.Address,
+		"地铁":   stringOrNil(r.Metro),
+		"电话":   r.Phone,
+		"营业时间": r.OpeningHours,
+		"推荐菜":  r.Dishes,
+		"人均消费": r.PricePerPerson,
+		"评分":   floatOrNil(r.Rating),
+	}
+	r.addAttributes(attrs)
+	return attrs
+}
+
+type Hotel struct {
+	Name       string   `json:"名称"`
+	Type       string   `json:"酒店类型"`
+	Address    string   `json:"地址"`
+	Metro      *string  `json:"地铁"`
+	Phone      string   `json:"电话"`
+	Facilities []string `json:"酒店设施"`
+	Price      *int     `json:"价格"`
+	Rating     float64  `json:"评分"`
+	Nearby
+}
+
+func (h *Hotel) EntityName() string   { return h.Name }
+func (h *Hotel) EntityDomain() string { return DomainHotel }
+func (h *Hotel) Attributes() map[string]interface{} {
+	attrs := map[string]interface{}{
+		"名称":   h.Name,
+		"酒店类型": h.Type,
+		"地址":   h.Address,
+		"地铁":   stringOrNil(h.Metro),
+		"电话":   h.Phone,
+		"酒店设施": h.Facilities,
+		"价格":   intOrNil(h.Price),
+		"评分":   h.Rating,
+	}
+	h.addAttributes(attrs)
+	return attrs
+}
+
+// HasFacility for the boolean slots 酒店设施-XXX
+func (h *Hotel) HasFacility(facility string) bool {
+	for _, f := range h.Facilities {
+		if f == facility {
+			return true
+		}
+	}
+	return false
+}
+
+// MetroStation is the nearest metro station of a place, the place can be of any other domain
+type MetroStation struct {
+	Name    string  `json:"名称"`
+	Station *string `json:"地铁"`
+}
+
+func (m *MetroStation) EntityName() string   { return m.Name }
+func (m *MetroStation) EntityDomain() string { return DomainMetro }
+func (m *MetroStation) Attributes() map[string]interface{} {
+	return map[string]interface{}{
+		"名称": m.Name,
+		"地铁": stringOrNil(m.Station),
+	}
+}
+
+// TaxiTemplate is not queried, 车型 and 车牌 are placeholders (#CX, #CP)
+type TaxiTemplate struct {
+	Name    string `json:"-"` // 出租 ($出发地 - $目的地)
+	CarType string `json:"车型"`
+	Plate   string `json:"车牌"`
+}
+
+func (t *TaxiTemplate) EntityName() string   { return t.Name }
+func (t *TaxiTemplate) EntityDomain() string { return DomainTaxi }
+func (t *TaxiTemplate) Attributes() map[string]interface{} {
+	return map[string]interface{}{
+		"车型": t.CarType,
+		"车牌": t.Plate,
+	}
+}
+
+func stringOrNil(v *string) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func intOrNil(v *int) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
+
+func floatOrNil(v *float64) interface{} {
+	if v == nil {
+		return nil
+	}
+	return *v
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/framely/sgdnlu/generate_framely/sgd"
 	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
 	"github.com/naturali/CrossWOZ/generate_framely/dialog"
+	"github.com/naturali/CrossWOZ/generate_framely/generate"
 	"io/ioutil"
 	"log"
 	"os"
@@ -135,14 +136,14 @@ func groupSlots(slotGroups map[string]*SlotGroup, inputDir string, inputFileName
 	return groups
 }
 
-// 把每个domain当成一个skill
-func GenerateIntents(groups map[string][]*MergedSlotGroup, inputFileName string, outputDir string) {
+// 把每个domain当成一个skill, responses and slot prompts are mined from sys turns by domain
+func GenerateIntents(groups map[string][]*MergedSlotGroup, miner *generate.ResponseMiner, prompts *generate.PromptMiner, inputFileName string, outputDir string) {
 	var intents []*p.IntentMeta
 	for _, group := range groups {
 		intent := &p.IntentMeta{
 			MetaId:          "CrossWOZ." + group[0].GroupName,
 			Name:            group[0].GroupName,
-			Responses:       nil, // TODO
+			Responses:       miner.Responses(group[0].GroupName, generate.DefaultTopResponses),
 			Type:            "intent",
 			DataQueries:     nil,
 			NewSuggestHooks: nil,
@@ -167,17 +168,13 @@ func GenerateIntents(groups map[string][]*MergedSlotGroup, inputFileName string,
 		}
 		for slotName, multi := range slotMap {
 			intent.Slots = append(intent.Slots, &p.FramelySlot{
-				Name:              slotName,
-				AttributeId:       slotName,
-				TypeId:            "",    // TODO
-				AllowAskSlot:      true,  // TODO
-				AskSlotPrompt:     nil,   // TODO
-				AllowConfirm:      false, // TODO we should get this from dialogue
-				ConfirmPrompts:    nil,
-				AllowMultiValue:   multi,
-				MultiValuePrompts: nil,
-				AllowUnknown:      false, // TODO
-				AllowSubtype:      false, // TODO
+				Name:            slotName,
+				AttributeId:     slotName,
+				TypeId:          "",   // TODO
+				AllowAskSlot:    true, // TODO
+				AllowMultiValue: multi,
+				AllowUnknown:    false, // TODO
+				AllowSubtype:    false, // TODO
 			})
 		}
 		sort.Slice(intent.Slots, func(i, j int) bool {
@@ -188,6 +185,11 @@ func GenerateIntents(groups map[string][]*MergedSlotGroup, inputFileName string,
 	sort.Slice(intents, func(i, j int) bool {
 		return intents[i].Name < intents[j].Name
 	})
+	// the prompts of the slots are mined from sys turns, then overridden by hand
+	prompts.Attach(intents, generate.DefaultTopResponses)
+	if unknown := generate.SlotPromptOverrides.Apply(intents); len(unknown) > 0 {
+		log.Println("prompt overrides of unknown slots:", unknown)
+	}
 	agent := p.Agent{
 		Agent: &p.DHLAgentMeta{
 			AgentId:     "CrossWOZ",
@@ -290,33 +292,52 @@ func listDomainCombinations(rawDialogues map[string]*crosswoz.RawDialogue, input
 	ioutil.WriteFile("generate_framely/demo/domain_combinations_"+inputFile+".json", b, 0666)
 }
 
+// ExtractExpressions makes expressions of turns, turns of more than one intent are handled by generate.MultiIntents
 func ExtractExpressions(dialogues []*crosswoz.Dialogue, outputDir string, inputFile string) {
 	var expressions []*p.FramelyExpression
+	report := generate.NewIntentTurnReport(generate.MultiIntents)
 	for _, dialog := range dialogues {
 		for _, msg := range dialog.Turns {
 			relatedIntents := msg.RelatedIntents()
-			if len(relatedIntents) > 1 {
-				log.Fatal("一次触发两个intent？", relatedIntents, msg.Utterance, dialog.DialogueID)
-			}
+			report.Add(relatedIntents)
 			if len(relatedIntents) == 0 {
 				log.Println("没有触发 intent？", relatedIntents, msg.Utterance, dialog.DialogueID)
 				continue
 			}
-			exp := &p.FramelyExpression{
-				Utterance: msg.Utterance,
-				OwnerId:   relatedIntents[0],
+			owners := relatedIntents
+			if len(relatedIntents) > 1 {
+				log.Println("一次触发多个intent：", relatedIntents, msg.Utterance, dialog.DialogueID, generate.MultiIntents)
+				switch generate.MultiIntents {
+				case generate.MultiIntentMerge:
+					owners = []string{generate.MultiOwnerId(relatedIntents)}
+				case generate.MultiIntentSkip:
+					continue
+				}
 			}
-			expressions = append(expressions, exp)
 			relatedSlots := msg.RelatedSlots()
-			for slotName, slotValue := range relatedSlots.InformedSlots {
-				anno := sgd.ExtractAnnotation(msg.Utterance, slotName, []string{slotValue}, dialog.DialogueID)
-				if anno != nil {
-					exp.Annotations = append(exp.Annotations, anno)
+			for _, owner := range owners {
+				exp := &p.FramelyExpression{
+					Utterance: msg.Utterance,
+					OwnerId:   owner,
+				}
+				expressions = append(expressions, exp)
+				for slotName, slotValue := range relatedSlots.InformedSlots {
+					// a merged expression has the slots of all the intents
+					if len(owners) > 1 && !strings.HasPrefix(slotName, owner+".") {
+						continue
+					}
+					anno := sgd.ExtractAnnotation(msg.Utterance, slotName, []string{slotValue}, dialog.DialogueID)
+					if anno != nil {
+						exp.Annotations = append(exp.Annotations, anno)
+					}
 				}
 			}
 		}
 	}
+	log.Printf("%d single intent and %d multiple intent turns, %s", report.SingleIntent, report.MultiIntent, report.Policy)
+	b, _ := json.MarshalIndent(report, "", "  ")
 	os.MkdirAll(path.Join(outputDir, inputFile), 0755)
+	ioutil.WriteFile(path.Join(outputDir, inputFile, "intent_turns_report.json"), b, 0666)
 	framely.OutputExpressions(expressions, outputDir, inputFile)
 
 }
@@ -389,7 +410,15 @@ func main() {
 
 	mergedSlotGroups := AnalyseGoals(dialogues, inputDir, inputFileName)
 
-	GenerateIntents(mergedSlotGroups, inputFileName, outputDir)
+	miner, err := generate.MineResponses(crosswoz.NewSliceIterator(dialogues))
+	if err != nil {
+		log.Fatal(err)
+	}
+	prompts, err := generate.MinePrompts(crosswoz.NewSliceIterator(dialogues))
+	if err != nil {
+		log.Fatal(err)
+	}
+	GenerateIntents(mergedSlotGroups, miner, prompts, inputFileName, outputDir)
 
 	fileName := path.Join(inputDir, "processed_"+inputFileName+".json")
 	b, err := json.MarshalIndent(dialogues, "", "  ")
@@ -24,25 +24,35 @@ var (
 )
 
 func AnalyseUserTurnActCombinations(dialogues []*crosswoz.Dialogue, inputFile string, outputDir string) {
-	AggregateUserTurns(dialogues, inputFile, outputDir, "userActCombinations", func(turn *crosswoz.Message, turnIdx int) string {
-		acts := turn.GetDialogActs()
-		return strings.Join(acts, ",")
-	}, false)
+	aggregateUserTurns(crosswoz.NewSliceIterator(dialogues), inputFile, outputDir, actCombinationAggregators()...)
 }
 
 func AnalyseUserTurnsREQUEST(dialogues []*crosswoz.Dialogue, inputFile string, outputDir string) {
-	AggregateUserTurns(dialogues, inputFile, outputDir, "userRequestedSlots", slotExtractorForSpecificAct("Request"), true)
-	AggregateUserTurns(dialogues, inputFile, outputDir, "userRequestedIntentss", intentExtractorForSpecificAct("Request"), true)
+	aggregateUserTurns(crosswoz.NewSliceIterator(dialogues), inputFile, outputDir, actAggregators("Request", "userRequestedSlots", "userRequestedIntentss")...)
 }
 
 func AnalyseUserTurnsSELECT(dialogues []*crosswoz.Dialogue, inputFile string, outputDir string) {
-	AggregateUserTurns(dialogues, inputFile, outputDir, "userSelectedSlots", slotExtractorForSpecificAct("Select"), true)
-	AggregateUserTurns(dialogues, inputFile, outputDir, "useSelectedIntents", intentExtractorForSpecificAct("Select"), true)
+	aggregateUserTurns(crosswoz.NewSliceIterator(dialogues), inputFile, outputDir, actAggregators("Select", "userSelectedSlots", "useSelectedIntents")...)
 }
 
 func AnalyseUserTurnsINFORM(dialogues []*crosswoz.Dialogue, inputFile string, outputDir string) {
-	AggregateUserTurns(dialogues, inputFile, outputDir, "userInformedSlots", slotExtractorForSpecificAct("Inform"), true)
-	AggregateUserTurns(dialogues, inputFile, outputDir, "userInformedIntents", intentExtractorForSpecificAct("Inform"), true)
+	aggregateUserTurns(crosswoz.NewSliceIterator(dialogues), inputFile, outputDir, actAggregators("Inform", "userInformedSlots", "userInformedIntents")...)
+}
+
+func actCombinationAggregators() []*userTurnAggregator {
+	return []*userTurnAggregator{
+		newUserTurnAggregator("userActCombinations", func(turn *crosswoz.Message, turnIdx int) string {
+			acts := turn.GetDialogActs()
+			return strings.Join(acts, ",")
+		}, false),
+	}
+}
+
+func actAggregators(actType string, slotSubject string, intentSubject string) []*userTurnAggregator {
+	return []*userTurnAggregator{
+		newUserTurnAggregator(slotSubject, slotExtractorForSpecificAct(actType), true),
+		newUserTurnAggregator(intentSubject, intentExtractorForSpecificAct(actType), true),
+	}
 }
 
 func slotExtractorForSpecificAct(actType string) func(turn *crosswoz.Message, turnIdx int) string {
@@ -82,38 +92,82 @@ func intentExtractorForSpecificAct(actType string) func(turn *crosswoz.Message,
 }
 
 func AggregateUserTurns(dialogues []*crosswoz.Dialogue, inputFile string, outputDir string, subject string, subjectExtractor func(turn *crosswoz.Message, turnIdx int) string, ignoreEmptySubject bool) {
-	var aggregationForAllDialogues = make(map[string]*Cnt)
-	for _, dialog := range dialogues {
-		log.Println("----- analysing", dialog.DialogueID, "for subject:", subject)
-		seen := make(map[string]bool)
-		for i, turn := range dialog.Turns {
-			if turn.Speaker != "usr" {
-				continue
-			}
-			subjectValue := subjectExtractor(turn, i)
-			if ignoreEmptySubject && subjectValue == "" {
-				continue
+	aggregateUserTurns(crosswoz.NewSliceIterator(dialogues), inputFile, outputDir, newUserTurnAggregator(subject, subjectExtractor, ignoreEmptySubject))
+}
+
+// AggregateUserTurnsFrom is like AggregateUserTurns, but consumes the dialogues one by one
+func AggregateUserTurnsFrom(it crosswoz.DialogueIterator, inputFile string, outputDir string, subject string, subjectExtractor func(turn *crosswoz.Message, turnIdx int) string, ignoreEmptySubject bool) error {
+	return aggregateUserTurns(it, inputFile, outputDir, newUserTurnAggregator(subject, subjectExtractor, ignoreEmptySubject))
+}
+
+type userTurnAggregator struct {
+	subject            string
+	subjectExtractor   func(turn *crosswoz.Message, turnIdx int) string
+	ignoreEmptySubject bool
+	aggregation        map[string]*Cnt
+}
+
+func newUserTurnAggregator(subject string, subjectExtractor func(turn *crosswoz.Message, turnIdx int) string, ignoreEmptySubject bool) *userTurnAggregator {
+	return &userTurnAggregator{
+		subject:            subject,
+		subjectExtractor:   subjectExtractor,
+		ignoreEmptySubject: ignoreEmptySubject,
+		aggregation:        make(map[string]*Cnt),
+	}
+}
+
+// aggregateUserTurns goes through the dialogues only once for all the aggregators
+func aggregateUserTurns(it crosswoz.DialogueIterator, inputFile string, outputDir string, aggregators ...*userTurnAggregator) error {
+	err := crosswoz.ForEachDialogue(it, nil, func(dialog *crosswoz.Dialogue) {
+		for _, aggregator := range aggregators {
+			aggregator.add(dialog)
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, aggregator := range aggregators {
+		aggregator.write(inputFile, outputDir)
+	}
+	return nil
+}
+
+func (aggregator *userTurnAggregator) add(dialog *crosswoz.Dialogue) {
+	subject := aggregator.subject
+	aggregationForAllDialogues := aggregator.aggregation
+	log.Println("----- analysing", dialog.DialogueID, "for subject:", subject)
+	seen := make(map[string]bool)
+	for i, turn := range dialog.Turns {
+		if turn.Speaker != "usr" {
+			continue
+		}
+		subjectValue := aggregator.subjectExtractor(turn, i)
+		if aggregator.ignoreEmptySubject && subjectValue == "" {
+			continue
+		}
+		//log.Println(subjectValue)
+		if _, ok := aggregationForAllDialogues[subjectValue]; !ok {
+			aggregationForAllDialogues[subjectValue] = &Cnt{
+				Turns:      1,
+				Dialogues:  1,
+				Utterances: []string{turn.Utterance},
 			}
-			//log.Println(subjectValue)
-			if _, ok := aggregationForAllDialogues[subjectValue]; !ok {
-				aggregationForAllDialogues[subjectValue] = &Cnt{
-					Turns:      1,
-					Dialogues:  1,
-					Utterances: []string{turn.Utterance},
-				}
-			} else {
-				aggregationForAllDialogues[subjectValue].Turns++
-				aggregationForAllDialogues[subjectValue].Utterances = sgd.AppendIfNotExists(aggregationForAllDialogues[subjectValue].Utterances, turn.Utterance)
-				if _, ok := seen[subjectValue]; !ok {
-					log.Println("new seen", subject, ":", subjectValue)
-					aggregationForAllDialogues[subjectValue].Dialogues++
-				}
+		} else {
+			aggregationForAllDialogues[subjectValue].Turns++
+			aggregationForAllDialogues[subjectValue].Utterances = sgd.AppendIfNotExists(aggregationForAllDialogues[subjectValue].Utterances, turn.Utterance)
+			if _, ok := seen[subjectValue]; !ok {
+				log.Println("new seen", subject, ":", subjectValue)
+				aggregationForAllDialogues[subjectValue].Dialogues++
 			}
-			seen[subjectValue] = true
 		}
+		seen[subjectValue] = true
 	}
+}
+
+func (aggregator *userTurnAggregator) write(inputFile string, outputDir string) {
+	subject := aggregator.subject
 	os.MkdirAll(path.Join(outputDir, inputFile, "dialogue_aggregate"), 0755)
-	b, _ := json.MarshalIndent(aggregationForAllDialogues, "", "  ")
+	b, _ := json.MarshalIndent(aggregator.aggregation, "", "  ")
 	outputFile := path.Join(outputDir, inputFile, "dialogue_aggregate", subject+".json")
 	if err := ioutil.WriteFile(outputFile, b, 0755); err != nil {
 		log.Fatal("Failed to write file ", outputFile, err)
@@ -122,8 +176,15 @@ func AggregateUserTurns(dialogues []*crosswoz.Dialogue, inputFile string, output
 }
 
 func AnalyseUserTurns(dialogues []*crosswoz.Dialogue, inputFile string, outputDir string) {
-	AnalyseUserTurnActCombinations(dialogues, inputFile, outputDir)
-	AnalyseUserTurnsREQUEST(dialogues, inputFile, outputDir)
-	AnalyseUserTurnsSELECT(dialogues, inputFile, outputDir)
-	AnalyseUserTurnsINFORM(dialogues, inputFile, outputDir)
+	AnalyseUserTurnsFrom(crosswoz.NewSliceIterator(dialogues), inputFile, outputDir)
+}
+
+// AnalyseUserTurnsFrom runs all the analyses in a single pass over the dialogues
+func AnalyseUserTurnsFrom(it crosswoz.DialogueIterator, inputFile string, outputDir string) error {
+	var aggregators []*userTurnAggregator
+	aggregators = append(aggregators, actCombinationAggregators()...)
+	aggregators = append(aggregators, actAggregators("Request", "userRequestedSlots", "userRequestedIntentss")...)
+	aggregators = append(aggregators, actAggregators("Select", "userSelectedSlots", "useSelectedIntents")...)
+	aggregators = append(aggregators, actAggregators("Inform", "userInformedSlots", "userInformedIntents")...)
+	return aggregateUserTurns(it, inputFile, outputDir, aggregators...)
 }
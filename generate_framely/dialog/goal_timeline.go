@@ -0,0 +1,42 @@
+package dialog
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+// WriteGoalTimelines replays the user goal of every dialogue and writes the timelines to
+// outputDir/inputFile/goal_timeline.json
+func WriteGoalTimelines(it crosswoz.DialogueIterator, inputFile string, outputDir string) error {
+	var timelines []*crosswoz.GoalTimeline
+	reached := 0
+	err := crosswoz.ForEachDialogue(it, nil, func(dialog *crosswoz.Dialogue) {
+		timeline, err := crosswoz.TrackGoal(dialog)
+		if err != nil {
+			log.Println("Failed to track goal, skipped:", err)
+			return
+		}
+		if timeline.FinalGoalReachedAt != -1 {
+			reached++
+		}
+		timelines = append(timelines, timeline)
+	})
+	if err != nil {
+		return err
+	}
+	log.Printf("%d of %d dialogues reached the final goal", reached, len(timelines))
+
+	os.MkdirAll(path.Join(outputDir, inputFile), 0755)
+	b, _ := json.MarshalIndent(timelines, "", "  ")
+	outputFile := path.Join(outputDir, inputFile, "goal_timeline.json")
+	if err := ioutil.WriteFile(outputFile, b, 0755); err != nil {
+		return err
+	}
+	log.Println("Wrote goal timelines to", outputFile)
+	return nil
+}
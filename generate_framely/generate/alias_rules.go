@@ -0,0 +1,122 @@
+package generate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"sort"
+)
+
+// AliasRule gives the aliases of slot values, how users may say a value in the utterance
+type AliasRule struct {
+	Name     string   `json:"name"`
+	Slots    []string `json:"slots,omitempty"` // the rule applies to these slots, all slots if empty
+	Priority int      `json:"priority"`        // rules of higher priority are tried first
+	// exactly one of Value and Pattern
+	Value   string   `json:"value,omitempty"`   // literal override, the rule applies to this value only
+	Pattern string   `json:"pattern,omitempty"` // regex, the rule applies to the values it matches
+	Aliases []string `json:"aliases"`           // templates, ${1} expands the groups of Pattern
+
+	re    *regexp.Regexp
+	slots map[string]bool
+}
+
+// AliasRules are sorted by priority, rules of the same priority keep the order of the file
+type AliasRules struct {
+	Rules []*AliasRule `json:"rules"`
+}
+
+//go:embed alias_rules.json
+var defaultAliasRules []byte
+
+// SpanAliasRules are used by findSpan, the default is alias_rules.json
+var SpanAliasRules = mustParseAliasRules(defaultAliasRules)
+
+func mustParseAliasRules(b []byte) *AliasRules {
+	rules, err := ParseAliasRules(b)
+	if err != nil {
+		panic(err)
+	}
+	return rules
+}
+
+// LoadAliasRules reads a rule file in the format of alias_rules.json
+func LoadAliasRules(fileName string) (*AliasRules, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s, err: %w", fileName, err)
+	}
+	rules, err := ParseAliasRules(b)
+	if err != nil {
+		return nil, fmt.Errorf("bad alias rules in %s, err: %w", fileName, err)
+	}
+	return rules, nil
+}
+
+func ParseAliasRules(b []byte) (*AliasRules, error) {
+	rules := &AliasRules{}
+	if err := json.Unmarshal(b, rules); err != nil {
+		return nil, err
+	}
+	for i, rule := range rules.Rules {
+		if (rule.Value == "") == (rule.Pattern == "") {
+			return nil, fmt.Errorf("rule %d %s: expect either value or pattern", i, rule.Name)
+		}
+		if len(rule.Aliases) == 0 {
+			return nil, fmt.Errorf("rule %d %s: no aliases", i, rule.Name)
+		}
+		if rule.Pattern != "" {
+			re, err := regexp.Compile(rule.Pattern)
+			if err != nil {
+				return nil, fmt.Errorf("rule %d %s: %w", i, rule.Name, err)
+			}
+			rule.re = re
+		}
+		rule.slots = make(map[string]bool)
+		for _, slot := range rule.Slots {
+			rule.slots[slot] = true
+		}
+	}
+	sort.SliceStable(rules.Rules, func(i, j int) bool {
+		return rules.Rules[i].Priority > rules.Rules[j].Priority
+	})
+	return rules, nil
+}
+
+// Apply returns the aliases of the value, nil if the rule does not apply
+func (rule *AliasRule) Apply(slotName string, slotValue string) []string {
+	if len(rule.slots) > 0 && !rule.slots[slotName] {
+		return nil
+	}
+	if rule.re == nil {
+		if slotValue != rule.Value {
+			return nil
+		}
+		return rule.Aliases
+	}
+	if !rule.re.MatchString(slotValue) {
+		return nil
+	}
+	var aliases []string
+	for _, template := range rule.Aliases {
+		aliases = append(aliases, rule.re.ReplaceAllString(slotValue, template))
+	}
+	return aliases
+}
+
+// Aliases of the value by all the rules in the order of priority, without the value itself and duplicates
+func (rules *AliasRules) Aliases(slotName string, slotValue string) []string {
+	var aliases []string
+	seen := map[string]bool{slotValue: true}
+	for _, rule := range rules.Rules {
+		for _, alias := range rule.Apply(slotName, slotValue) {
+			if alias != "" && !seen[alias] {
+				seen[alias] = true
+				aliases = append(aliases, alias)
+			}
+		}
+	}
+	return aliases
+}
@@ -96,27 +96,27 @@ func (domain *Domain) IntentMeta() *p.IntentMeta {
 				{
 					AttributeId:   intentID + "." + "出发地",
 					Name:          "出发地",
-					TypeId:        "System.String", // TODO? 什么类型，XX名称？父类？
+					TypeId:        "System.地名",
 					AllowAskSlot:  true,
 					AskSlotPrompt: []string{"从哪里出发？"},
 				},
 				{
 					AttributeId:  intentID + "." + "出发地附近地铁站",
 					Name:         "出发地附近地铁站",
-					TypeId:       "System.String", // TODO? 什么类型，地铁站？
+					TypeId:       "地铁站名",
 					AllowAskSlot: false,
 				},
 				{
 					AttributeId:   intentID + "." + "目的地",
 					Name:          "目的地",
-					TypeId:        "System.String", // TODO? 什么类型，XX名称？父类？
+					TypeId:        "System.地名",
 					AllowAskSlot:  true,
 					AskSlotPrompt: []string{"到哪里？"},
 				},
 				{
 					AttributeId:  intentID + "." + "目的地附近地铁站",
 					Name:         "目的地附近地铁站",
-					TypeId:       "System.String", // TODO? 什么类型，XX名称？父类？
+					TypeId:       "地铁站名",
 					AllowAskSlot: false,
 				},
 			},
@@ -164,6 +164,7 @@ func (domain *Domain) IntentMeta() *p.IntentMeta {
 			slot.MultiValuePrompts = []string{"还有哪些" + ent.Name}
 		}
 	}
+	intent.Slots = append(intent.Slots, selectSourceSlots(intentID)...)
 	sort.Slice(intent.Slots, func(i, j int) bool {
 		return intent.Slots[i].Name < intent.Slots[j].Name
 	})
@@ -0,0 +1,132 @@
+package generate
+
+import (
+	"log"
+	"strings"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+)
+
+// labels of System.Boolean expressions
+const (
+	BooleanYes = "YES"
+	BooleanNo  = "NO"
+)
+
+// BooleanSlots are the attribute ids of System.Boolean slots, like 酒店.酒店设施-健身房, see BooleanSlotsOf.
+// Without an agent, the slots are typed by entityID.
+var BooleanSlots map[string]bool
+
+// BooleanSlotsOf finds the System.Boolean slots of the agent
+func BooleanSlotsOf(agent *p.Agent) map[string]bool {
+	slots := make(map[string]bool)
+	for _, intent := range agent.Intents {
+		for _, slot := range intent.Slots {
+			if slot.TypeId == "System.Boolean" {
+				slots[slot.AttributeId] = true
+			}
+		}
+	}
+	return slots
+}
+
+// IsBoolean if the slot of the intent is a System.Boolean slot, which is answered by yes or no
+func IsBoolean(intent string, slotName string) bool {
+	if BooleanSlots != nil {
+		return BooleanSlots[intent+"."+slotName]
+	}
+	return entityID(intent, slotName) == "System.Boolean"
+}
+
+var (
+	yesValues = map[string]bool{"是": true, "有": true, "需要": true, "要": true}
+	noValues  = map[string]bool{"否": true, "没有": true, "无": true, "不需要": true, "不要": true}
+)
+
+// ParseBoolean parses the value of a boolean slot, ok is false if it's neither yes nor no
+func ParseBoolean(slotValue string) (yes bool, ok bool) {
+	if yesValues[slotValue] {
+		return true, true
+	}
+	if noValues[slotValue] {
+		return false, true
+	}
+	return false, false
+}
+
+// negations before a facility, like 没健身房也行, 不需要叫醒服务
+var negations = []string{"没有", "没", "不需要", "不用", "不要", "无需", "不必", "不提供"}
+
+// questions which look like negations, like 有没有健身房, 是否提供
+var negationQuestions = []string{"有没有", "有没", "需不需要", "要不要", "是否"}
+
+// how far before the facility a negation is looked for, in characters
+const negationWindow = 6
+
+// facilityName is the facility of a boolean slot, 酒店设施-健身房 -> 健身房
+func facilityName(slotName string) string {
+	if i := strings.Index(slotName, "-"); i != -1 {
+		return slotName[i+1:]
+	}
+	return slotName
+}
+
+// IsNegated tells if the facility of the slot is negated in the utterance, like 没健身房也行,
+// found is false if the facility is not in the utterance
+func IsNegated(utterance string, slotName string) (negated bool, found bool) {
+	i := strings.Index(utterance, facilityName(slotName))
+	if i == -1 {
+		return false, false
+	}
+	before := []rune(utterance[:i])
+	if len(before) > negationWindow {
+		before = before[len(before)-negationWindow:]
+	}
+	// 这家有没有健身房 is a question
+	window := string(before)
+	for _, question := range negationQuestions {
+		window = strings.Replace(window, question, strings.Repeat("#", len([]rune(question))), -1)
+	}
+	for _, negation := range negations {
+		if strings.Contains(window, negation) {
+			return true, true
+		}
+	}
+	return false, true
+}
+
+// BooleanExpressions makes a YES or NO expression for each boolean slot of the informed values.
+// The label follows the value, or the phrasing of the utterance if the value is neither yes nor no.
+func BooleanExpressions(utterance string, values *InformedSlotValues) (expressions []*p.FramelyExpression) {
+	for _, slotName := range sortedSlotNames(values.SlotValues) {
+		if !IsBoolean(values.Intent, slotName) {
+			continue
+		}
+		slotValue := values.SlotValues[slotName]
+		negated, found := IsNegated(utterance, slotName)
+		yes, ok := ParseBoolean(slotValue)
+		if !ok {
+			if !found {
+				log.Println("!!!!unknown boolean value:", utterance, slotName, slotValue)
+				continue
+			}
+			yes = !negated
+		} else if found && negated == yes {
+			log.Println("~~~~boolean value against the utterance:", utterance, slotName, slotValue)
+		}
+		exp := &p.FramelyExpression{
+			OwnerId:   "System.Boolean",
+			Utterance: utterance,
+			Label:     BooleanYes,
+		}
+		if !yes {
+			exp.Label = BooleanNo
+		}
+		exp.Context = &p.ExpressionContext{
+			FrameId:     values.Intent,
+			AttributeId: values.Intent + "." + slotName,
+		}
+		expressions = append(expressions, exp)
+	}
+	return expressions
+}
@@ -0,0 +1,89 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+func TestIsNegated(t *testing.T) {
+	for _, c := range []struct {
+		utterance, slotName string
+		negated, found      bool
+	}{
+		{"把这样吧，还是找经济型的酒店，没健身房也行，别太贵了。", "酒店设施-健身房", true, true},
+		{"不需要叫醒服务", "酒店设施-叫醒服务", true, true},
+		{"这家酒店有没有酒吧和租车服务？", "酒店设施-酒吧", false, true},
+		{"麻烦你帮我查一下这个酒店是否提供收费停车位和会议室好吗？", "酒店设施-收费停车位", false, true},
+		{"酒店最好有健身房。", "酒店设施-健身房", false, true},
+		{"没问题，帮我找一家四星的酒店，要有健身房", "酒店设施-健身房", false, true},
+		{"找一家酒店", "酒店设施-健身房", false, false},
+	} {
+		if negated, found := IsNegated(c.utterance, c.slotName); negated != c.negated || found != c.found {
+			t.Errorf("%s %s: expect %v %v, got %v %v", c.utterance, c.slotName, c.negated, c.found, negated, found)
+		}
+	}
+	if !IsBoolean("酒店", "酒店设施-健身房") || IsBoolean("酒店", "评分") {
+		t.Errorf("unexpected boolean slots")
+	}
+}
+
+func TestBooleanExpressions(t *testing.T) {
+	// real turns of CrossWOZ, by dialogue id and utterance
+	expects := map[string]map[string][]string{
+		"../../data/crosswoz/demo10034.json": {
+			"10034": {"YES 酒店.酒店设施-宽带上网", "YES 酒店.酒店设施-接待外宾"},
+		},
+		"../../data/crosswoz/test.json.zip": {
+			"8412": {"YES 酒店.酒店设施-健身房", "NO 酒店.酒店设施-健身房"},
+			"4666": {"YES 酒店.酒店设施-健身房"},
+		},
+	}
+	for fileName, dialogues := range expects {
+		reader, err := crosswoz.OpenDialogueReader(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = crosswoz.ForEachDialogue(reader, nil, func(dialogue *crosswoz.Dialogue) {
+			expect, ok := dialogues[dialogue.DialogueID]
+			if !ok {
+				return
+			}
+			var labels []string
+			for _, turn := range dialogue.Turns {
+				if turn.Speaker != "usr" {
+					continue
+				}
+				for _, exp := range ExtractExpressions(turn) {
+					if exp.OwnerId == "System.Boolean" {
+						labels = append(labels, exp.Label+" "+exp.Context.AttributeId)
+					}
+					// boolean values are not annotated
+					for _, anno := range exp.Annotations {
+						dot := strings.Index(anno.Label, ".")
+						if IsBoolean(anno.Label[:dot], anno.Label[dot+1:]) {
+							t.Errorf("%s: unexpected annotation %s", exp.Utterance, anno.Label)
+						}
+					}
+				}
+			}
+			if len(labels) != len(expect) {
+				t.Fatalf("%s: expect %v, got %v", dialogue.DialogueID, expect, labels)
+			}
+			for i := range labels {
+				if labels[i] != expect[i] {
+					t.Errorf("%s: expect %v, got %v", dialogue.DialogueID, expect, labels)
+				}
+			}
+			delete(dialogues, dialogue.DialogueID)
+		})
+		reader.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dialogues) > 0 {
+			t.Errorf("dialogues not found in %s: %v", fileName, dialogues)
+		}
+	}
+}
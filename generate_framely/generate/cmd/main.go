@@ -7,12 +7,16 @@ import (
 	"log"
 	"os"
 	"path"
+	"strings"
 
 	"github.com/framely/sgdnlu/generate_framely/framely"
 	"github.com/framely/sgdnlu/generate_framely/framely/p"
 	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/database"
 	"github.com/naturali/CrossWOZ/generate_framely/dialog"
 	"github.com/naturali/CrossWOZ/generate_framely/generate"
+	"github.com/naturali/CrossWOZ/generate_framely/nlg"
+	"github.com/naturali/CrossWOZ/generate_framely/verify"
 )
 
 var (
@@ -20,27 +24,198 @@ var (
 		"\t expression: generate expressions for the overall agent\n"+
 		"\t agent: generate agent based on the database files\n"+
 		"\t aggregate: aggregate dialogues to find out dialogue act combinations and intent/slot combinations\n"+
-		"\t full: run the full task including all the above ones")
-	dialogueFile = flag.String("dialog-file", "test", "the dialogue json file name")
+		"\t goal-timeline: replay user states to find out how users work through their goals\n"+
+		"\t verify-selected: check selectedResults of sys turns against the database\n"+
+		"\t verify-inform: check values informed by sys turns against the database\n"+
+		"\t verify-nearby: check the symmetry of 周边 lists and the 周边 references of goals\n"+
+		"\t span-report: report how slot values are found in utterances, per slot and value\n"+
+		"\t responses: mine response templates from sys turns and generate the agent with them\n"+
+		"\t prompts: mine ask slot, multi value and confirm prompts from sys turns and generate the agent with them\n"+
+		"\t nlg: render the dialog acts of sys turns with templates, and evaluate BLEU and slot error rate against the turns\n"+
+		"\t all: run agent, aggregate, goal-timeline and expression")
+	offsetMode = flag.String("offset-mode", "bytes", "expression: unit of annotation offsets, bytes, runes or utf16. "+
+		"Expressions are converted to dollars only with bytes, otherwise they are written with annotations")
+	aliasRules     = flag.String("alias-rules", "", "expression, span-report: a rule file of slot value aliases, see generate/alias_rules.json for the default")
+	fuzzyThreshold = flag.Float64("fuzzy-threshold", 0.8, "expression, span-report: the lowest confidence of a fuzzy slot span, lower ones are written to span_review.json")
+	multiIntent    = flag.String("multi-intent", "split", "expression, span-report: what to do with user turns requesting more than one intent, "+
+		"split into an expression for each intent, merge into one expression of all the intents, or skip. "+
+		"No intent of the agent owns a merged expression like 景点+酒店, the expression mode writes them to multi_intent_expression.json")
+	promptOverrides = flag.String("prompt-overrides", "", "prompts: a file of hand written slot prompts, see generate/prompt_overrides.json for the default")
+	useSysStateInit = flag.Bool("sys-state-init", false, "verify-selected: use sys_state_init instead of sys_state")
+	nlgTemplates    = flag.String("nlg-templates", "", "nlg: a template file like agents/val/nlg_templates.json, mined from -nlg-train if empty")
+	nlgTrain        = flag.String("nlg-train", "val", "nlg: the dialogue file to mine templates from, like -dialog-file")
+	dialogueFile    = flag.String("dialog-file", "test", "the dialogue file, either a split name in data/crosswoz (test, val, train) "+
+		"or a path to a .json, .json.zip or .json.gz file")
 )
 
 func main() {
+	flag.Parse()
 	if *mode == "agent" || *mode == "all" {
 		agent := generate.GenerateAgent("data/crosswoz/database", "agents")
 		framely.OutputAgent(agent, "agents")
 
 		dialog.AllIntents, dialog.AllSlots = VerifyAgent(agent)
+		generate.BooleanSlots = generate.BooleanSlotsOf(agent)
 	}
 	if *mode == "aggregate" || *mode == "all" {
-		dialogues := crosswoz.ReadDialogues("data/crosswoz/test.json")
-		dialog.AnalyseUserTurns(dialogues, "test", "agents")
+		reader, inputFile := openDialogues(*dialogueFile)
+		if err := dialog.AnalyseUserTurnsFrom(reader, inputFile, "agents"); err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+	}
+	if *mode == "goal-timeline" || *mode == "all" {
+		reader, inputFile := openDialogues(*dialogueFile)
+		if err := dialog.WriteGoalTimelines(reader, inputFile, "agents"); err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+	}
+	if *mode == "verify-selected" {
+		db := loadDB()
+		reader, inputFile := openDialogues(*dialogueFile)
+		report, err := verify.VerifySelectedResults(db, reader, *useSysStateInit)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		log.Printf("%d of %d checked domain states contradict the database", len(report.Issues), report.CheckedDomains)
+		if err := verify.WriteReport(report, inputFile, "agents", "selected_results_report"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "verify-inform" {
+		db := loadDB()
+		reader, inputFile := openDialogues(*dialogueFile)
+		report, err := verify.VerifyInformValues(db, reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		for _, domainSlot := range report.SortedSlots() {
+			parts := strings.SplitN(domainSlot, ".", 2)
+			cnt := report.Slots[parts[0]][parts[1]]
+			log.Printf("%s: %d of %d informed values contradict the database", domainSlot, cnt.Mismatched, cnt.Checked)
+		}
+		if err := verify.WriteReport(report, inputFile, "agents", "inform_values_report"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "verify-nearby" {
+		graph := database.NewNearbyGraph(loadDB())
+		graphReport := graph.Validate()
+		log.Printf("%d asymmetric and %d dangling of %d 周边 references in the database",
+			len(graphReport.Asymmetric), len(graphReport.Dangling), graphReport.Edges)
+		reader, inputFile := openDialogues(*dialogueFile)
+		report, err := verify.VerifyNearbyReferences(graph, reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		log.Printf("%d of %d 周边 references of goals are not satisfied by final goals", len(report.Issues), report.References)
+		if err := verify.WriteReport(graphReport, inputFile, "agents", "nearby_graph_report"); err != nil {
+			log.Fatal(err)
+		}
+		if err := verify.WriteReport(report, inputFile, "agents", "nearby_references_report"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "responses" {
+		agent := generate.GenerateAgent("data/crosswoz/database", "agents")
+		reader, inputFile := openDialogues(*dialogueFile)
+		miner, err := generate.MineResponses(reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		miner.Attach(agent.Intents, generate.DefaultTopResponses)
+		for _, intent := range agent.Intents {
+			log.Printf("%s: %d responses", intent.MetaId, len(intent.Responses))
+		}
+		framely.OutputAgent(agent, "agents")
+		if err := verify.WriteReport(miner, inputFile, "agents", "response_templates"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "prompts" {
+		agent := generate.GenerateAgent("data/crosswoz/database", "agents")
+		generate.BooleanSlots = generate.BooleanSlotsOf(agent)
+		reader, inputFile := openDialogues(*dialogueFile)
+		miner, err := generate.MinePrompts(reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		miner.Attach(agent.Intents, generate.DefaultTopResponses)
+		if *promptOverrides != "" {
+			if generate.SlotPromptOverrides, err = generate.LoadPromptOverrides(*promptOverrides); err != nil {
+				log.Fatal(err)
+			}
+		}
+		if unknown := generate.SlotPromptOverrides.Apply(agent.Intents); len(unknown) > 0 {
+			log.Println("!!!!prompt overrides of unknown slots:", unknown)
+		}
+		log.Printf("%d ask slot, %d multi value and %d confirm prompt groups mined from %d sys turns, no prompts of %v",
+			len(miner.AskSlot), len(miner.MultiValue), len(miner.Confirm), miner.Turns, miner.Empty())
+		framely.OutputAgent(agent, "agents")
+		if err := verify.WriteReport(miner, inputFile, "agents", "slot_prompts"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "nlg" {
+		templates := nlgTemplateFile()
+		reader, inputFile := openDialogues(*dialogueFile)
+		report, err := nlg.Evaluate(templates, reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		log.Printf("%d of %d sys turns rendered, BLEU %.4f, slot error rate %.4f",
+			report.Rendered, report.Turns, report.BLEU, report.SlotErrorRate)
+		if err := verify.WriteReport(report, inputFile, "agents", "nlg_report"); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if *mode == "span-report" {
+		setupAnnotator()
+		generate.SpanCoverage = generate.NewSpanReport()
+		reader, inputFile := openDialogues(*dialogueFile)
+		if _, err := generate.GenerateExpressionsFrom(nil, nil, reader); err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		report := generate.SpanCoverage
+		log.Printf("%d exact, %d alias, %d fuzzy and %d missing of %d slot values",
+			report.Exact, report.Alias, report.Fuzzy, report.Missing, report.Total)
+		if err := generate.WriteSpanReport(report, "agents", inputFile); err != nil {
+			log.Fatal(err)
+		}
+		if err := generate.WriteSpanReviews("agents", inputFile); err != nil {
+			log.Fatal(err)
+		}
+		writeIntentTurns(inputFile)
 	}
 	if *mode == "expression" || *mode == "all" {
-		inputFile := *dialogueFile
-		dialogues := crosswoz.ReadDialogues("data/crosswoz/" + inputFile + ".json")
-		expressions := generate.GenerateExpressions(nil, nil, dialogues)
+		offsets := setupAnnotator()
+		reader, inputFile := openDialogues(*dialogueFile)
+		expressions, err := generate.GenerateExpressionsFrom(nil, nil, reader)
+		if err != nil {
+			log.Fatal(err)
+		}
+		reader.Close()
+		if err := generate.WriteSpanReviews("agents", inputFile); err != nil {
+			log.Fatal(err)
+		}
+		writeIntentTurns(inputFile)
+		if generate.MultiIntents == generate.MultiIntentMerge {
+			var merged []*p.FramelyExpression
+			expressions, merged = generate.SeparateMergedExpressions(expressions)
+			if err := generate.WriteMergedExpressions(merged, "agents", inputFile); err != nil {
+				log.Fatal(err)
+			}
+		}
 
-		if true {
+		if offsets == generate.OffsetBytes {
 			for _, exp := range expressions {
 				framely.ConvertExpressionAnnotationsToDollars(exp, func(annoLabel string) string {
 					return annoLabel
@@ -48,7 +223,7 @@ func main() {
 			}
 			framely.OutputExpressions(expressions, "agents", inputFile)
 		} else {
-			// only for debug, keep annotations, don't group by owners
+			// keep annotations for char-level taggers, don't group by owners
 			b, _ := json.MarshalIndent(expressions, "", "  ")
 			outputDir := path.Join("agents", inputFile)
 			os.MkdirAll(outputDir, 0755)
@@ -64,6 +239,84 @@ func main() {
 
 }
 
+// setupAnnotator sets the options of generate.ExtractSlotAnnotations from the flags
+func setupAnnotator() generate.OffsetMode {
+	offsets, err := generate.ParseOffsetMode(*offsetMode)
+	if err != nil {
+		log.Fatal(err)
+	}
+	generate.AnnotationOffsetMode = offsets
+	generate.FuzzyThreshold = *fuzzyThreshold
+	if generate.MultiIntents, err = generate.ParseMultiIntentPolicy(*multiIntent); err != nil {
+		log.Fatal(err)
+	}
+	generate.IntentTurns = generate.NewIntentTurnReport(generate.MultiIntents)
+	generate.ExpressionContexts = generate.NewContextReport()
+	if *aliasRules != "" {
+		if generate.SpanAliasRules, err = generate.LoadAliasRules(*aliasRules); err != nil {
+			log.Fatal(err)
+		}
+	}
+	return offsets
+}
+
+// writeIntentTurns reports the user turns by the number of requested intents, and how the contexts are derived
+func writeIntentTurns(inputFile string) {
+	report := generate.IntentTurns
+	log.Printf("%d of %d user turns request more than one intent, %s", report.MultiIntent, report.Turns, report.Policy)
+	if err := verify.WriteReport(report, inputFile, "agents", "intent_turns_report"); err != nil {
+		log.Fatal(err)
+	}
+	log.Println("contexts of expressions:", generate.ExpressionContexts.Sources)
+	if err := verify.WriteReport(generate.ExpressionContexts, inputFile, "agents", "expression_context_report"); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// nlgTemplateFile loads the templates of -nlg-templates, or mines and writes them from -nlg-train
+func nlgTemplateFile() nlg.Templates {
+	if *nlgTemplates != "" {
+		templates, err := nlg.LoadTemplates(*nlgTemplates)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return templates
+	}
+	reader, inputFile := openDialogues(*nlgTrain)
+	templates, err := nlg.Mine(reader, nlg.DefaultTopTemplates, generate.MinResponseCount)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reader.Close()
+	log.Printf("mined templates of %d dialog act combinations", len(templates))
+	if err := verify.WriteReport(templates, inputFile, "agents", "nlg_templates"); err != nil {
+		log.Fatal(err)
+	}
+	return templates
+}
+
+func loadDB() *database.DB {
+	db, err := database.Load("data/crosswoz/database")
+	if err != nil {
+		log.Fatal(err)
+	}
+	return db
+}
+
+// openDialogues opens a split name or a dialogue file path, also returns the split name for output
+func openDialogues(nameOrPath string) (*crosswoz.DialogueReader, string) {
+	fileName, err := crosswoz.ResolveDialogueFile("data/crosswoz", nameOrPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+	reader, err := crosswoz.OpenDialogueReader(fileName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	log.Println("Reading dialogues from", fileName)
+	return reader, crosswoz.DialogueSplitName(fileName)
+}
+
 func VerifyAgent(agent *p.Agent) (allIntentIDs map[string]bool, allSlotIDs map[string]bool) {
 	allTypeIDs := make(map[string]bool)
 	// check entities, should not have duplicated type ids
@@ -0,0 +1,125 @@
+package generate
+
+import (
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+// how the context of an expression is derived
+const (
+	ContextSysRequest   = "sys-request"   // answers the slot asked by the question of the previous sys turn, see askingClause
+	ContextSysRecommend = "sys-recommend" // picks a name recommended by the previous sys turn
+	ContextSysTurn      = "sys-turn"      // goes on with an intent of the previous sys turn
+	ContextHistory      = "history"       // goes on with an intent of an earlier turn
+	ContextFirstTrigger = "first-trigger" // the first turn of the intent in the dialogue, without context
+	ContextSelect       = "select"        // a Select act, see selectContext
+	ContextHeuristic    = "heuristic"     // without the dialogue, by whether 名称 is requested
+	ContextMerged       = "merged"        // merged from intents of different contexts, without context, see mergeExpressions
+)
+
+// DialogueContext is what a user turn follows in the dialogue
+type DialogueContext struct {
+	PrevSys *crosswoz.Message // the previous sys turn, nil at the beginning
+	asking  string            // the clause asking of the question of PrevSys, see askingClause
+	seen    map[string]bool   // intents of the earlier turns
+}
+
+func NewDialogueContext() *DialogueContext {
+	return &DialogueContext{seen: make(map[string]bool)}
+}
+
+// Observe adds a turn to the context after its expressions are extracted
+func (c *DialogueContext) Observe(turn *crosswoz.Message) {
+	for _, act := range turn.DialogActs {
+		if act.Act != "General" {
+			c.seen[act.Intent] = true
+		}
+	}
+	if turn.Speaker == "sys" {
+		c.PrevSys = turn
+		c.asking = askingClause(turn, lastSentence(turn.Utterance))
+	}
+}
+
+// contextOf derives the context of the expression of the intent, and how it is derived.
+// A nil DialogueContext falls back to the heuristic of ContextHeuristic.
+func (c *DialogueContext) contextOf(detail *DialogueActsDetail, intent string, triggering bool) (*p.ExpressionContext, string) {
+	if source, ok := detail.SelectedSources[intent]; ok {
+		return selectContext(intent, source), ContextSelect
+	}
+	if c == nil {
+		// 是不是真的第一次触发intent
+		if _, ok := detail.RequestedSlots[intent]["名称"]; triggering && (ok || intent == "地铁" || intent == "出租") {
+			return nil, ContextHeuristic
+		}
+		return &p.ExpressionContext{FrameId: intent}, ContextHeuristic
+	}
+	informed := detail.SlotValuesOf(intent)
+	// CrossWOZ has no sys Request acts, the sys asks by a question
+	if c.asking != "" {
+		slots := make(map[string]bool)
+		for slot := range informed {
+			slots[slot] = true
+		}
+		for _, slot := range crosswoz.MapKeysSorted(slots) {
+			if asksFor(c.asking, slot) {
+				return &p.ExpressionContext{FrameId: intent, AttributeId: intent + "." + slot}, ContextSysRequest
+			}
+		}
+	}
+	if c.PrevSys != nil {
+		for _, act := range c.PrevSys.DialogActs {
+			if act.Intent == intent && act.Act == "Recommend" && informed["名称"] == act.Value {
+				return &p.ExpressionContext{FrameId: intent, AttributeId: intent + ".名称"}, ContextSysRecommend
+			}
+		}
+	}
+	if !c.seen[intent] {
+		return nil, ContextFirstTrigger
+	}
+	if c.PrevSys != nil {
+		for _, prevIntent := range c.PrevSys.RelatedIntents() {
+			if prevIntent == intent {
+				return &p.ExpressionContext{FrameId: intent}, ContextSysTurn
+			}
+		}
+	}
+	return &p.ExpressionContext{FrameId: intent}, ContextHistory
+}
+
+// ContextReport counts expressions by how their contexts are derived
+type ContextReport struct {
+	Expressions int
+	Sources     map[string]int            // by ContextSysRequest and so on
+	Intents     map[string]map[string]int // by intent, then by how
+}
+
+func NewContextReport() *ContextReport {
+	return &ContextReport{Sources: make(map[string]int), Intents: make(map[string]map[string]int)}
+}
+
+func (r *ContextReport) Add(intent string, how string) {
+	r.Expressions++
+	r.Sources[how]++
+	if _, ok := r.Intents[intent]; !ok {
+		r.Intents[intent] = make(map[string]int)
+	}
+	r.Intents[intent][how]++
+}
+
+// ExpressionContexts collects how ExtractExpressions derives contexts, nil to not collect
+var ExpressionContexts *ContextReport
+
+// setContext sets the context of the expression of the intent, and returns how it is derived
+func (c *DialogueContext) setContext(exp *p.FramelyExpression, detail *DialogueActsDetail, intent string, triggering bool) string {
+	context, how := c.contextOf(detail, intent, triggering)
+	exp.Context = context
+	return how
+}
+
+// countContext counts the context of an emitted expression in ExpressionContexts
+func countContext(exp *p.FramelyExpression, how string) {
+	if ExpressionContexts != nil {
+		ExpressionContexts.Add(exp.OwnerId, how)
+	}
+}
@@ -0,0 +1,100 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz/crosswoztest"
+)
+
+func TestExpressionContexts(t *testing.T) {
+	defer func(report *ContextReport) {
+		ExpressionContexts = report
+	}(ExpressionContexts)
+	ExpressionContexts = NewContextReport()
+
+	turn := crosswoztest.Turn
+	dialogue := &crosswoz.Dialogue{Turns: []*crosswoz.Message{
+		turn("usr", "找一个评分4.5分以上的景点", [4]string{"Inform", "景点", "评分", "4.5分以上"}, [4]string{"Request", "景点", "名称", ""}),
+		turn("sys", "故宫和天坛都不错", [4]string{"Recommend", "景点", "名称", "故宫"}, [4]string{"Recommend", "景点", "名称", "天坛"}),
+		turn("usr", "就去故宫吧，门票多少钱", [4]string{"Inform", "景点", "名称", "故宫"}, [4]string{"Request", "景点", "门票", ""}),
+		turn("sys", "门票60元。您想住什么价位的酒店？", [4]string{"Inform", "景点", "门票", "60元"}),
+		turn("usr", "500-1000元的", [4]string{"Inform", "酒店", "价格", "500-1000元"}),
+		turn("sys", "推荐北京饭店", [4]string{"Recommend", "酒店", "名称", "北京饭店"}),
+		turn("usr", "故宫的地址是什么", [4]string{"Inform", "景点", "名称", "故宫"}, [4]string{"Request", "景点", "地址", ""}),
+	}}
+	expressions, err := GenerateExpressionsFrom(nil, nil, crosswoz.NewSliceIterator([]*crosswoz.Dialogue{dialogue}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expects := []struct{ owner, frame, attribute string }{
+		{"景点", "", ""}, // the first trigger has no context
+		{"景点", "景点", "景点.名称"},
+		{"酒店", "酒店", "酒店.价格"},
+		{"景点", "景点", ""},
+	}
+	if len(expressions) != len(expects) {
+		t.Fatalf("expect %d expressions, got %d", len(expects), len(expressions))
+	}
+	for i, expect := range expects {
+		exp := expressions[i]
+		frame, attribute := "", ""
+		if exp.Context != nil {
+			frame, attribute = exp.Context.FrameId, exp.Context.AttributeId
+		}
+		if exp.OwnerId != expect.owner || frame != expect.frame || attribute != expect.attribute {
+			t.Errorf("%s: expect %+v, got %s in %+v", exp.Utterance, expect, exp.OwnerId, exp.Context)
+		}
+	}
+	for how, cnt := range map[string]int{ContextFirstTrigger: 1, ContextSysRecommend: 1, ContextSysRequest: 1, ContextHistory: 1} {
+		if ExpressionContexts.Sources[how] != cnt {
+			t.Errorf("expect %d of %s, got %v", cnt, how, ExpressionContexts.Sources)
+		}
+	}
+
+	// without the dialogue
+	if exps := ExtractExpressions(turn("usr", "故宫的门票多少钱", [4]string{"Inform", "景点", "名称", "故宫"}, [4]string{"Request", "景点", "门票", ""})); exps[0].Context == nil || exps[0].Context.FrameId != "景点" {
+		t.Errorf("unexpected context of the heuristic: %+v", exps[0].Context)
+	}
+}
+
+func TestExpressionContextsOfSysQuestions(t *testing.T) {
+	defer func(report *ContextReport) {
+		ExpressionContexts = report
+	}(ExpressionContexts)
+	ExpressionContexts = NewContextReport()
+
+	reader, err := crosswoz.OpenDialogueReader("../../data/crosswoz/test.json.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	var dialogues []*crosswoz.Dialogue
+	if err := crosswoz.ForEachDialogue(reader, nil, func(dialogue *crosswoz.Dialogue) {
+		if dialogue.DialogueID == "12187" {
+			dialogues = append(dialogues, dialogue)
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if len(dialogues) != 1 {
+		t.Fatalf("expect the dialogue 12187, got %d", len(dialogues))
+	}
+	expressions, err := GenerateExpressionsFrom(nil, nil, crosswoz.NewSliceIterator(dialogues))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the sys asks 请问您要到达的地点是哪里？, and the user informs the places again
+	var answer *p.FramelyExpression
+	for _, exp := range expressions {
+		if strings.HasPrefix(exp.Utterance, "我想乘地铁从北京贵都大酒店到") {
+			answer = exp
+		}
+	}
+	if answer == nil || answer.Context == nil || answer.Context.AttributeId != "地铁.目的地" ||
+		ExpressionContexts.Intents["地铁"][ContextSysRequest] != 1 {
+		t.Errorf("unexpected context of %+v in %+v", answer, ExpressionContexts.Intents["地铁"])
+	}
+}
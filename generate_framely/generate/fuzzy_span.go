@@ -0,0 +1,202 @@
+package generate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+)
+
+// methods of fuzzy matching
+const (
+	FuzzyEditDistance = "edit_distance"
+	FuzzyLCS          = "lcs"
+)
+
+// FuzzyThreshold is the lowest confidence of a fuzzy span to be annotated, lower ones go to the review file
+var FuzzyThreshold = 0.8
+
+// values of at least this many characters, like restaurant names, are matched by longest common subsequence
+const fuzzyLongValue = 8
+
+// FuzzyMatch is the closest substring of the utterance to the slot value
+type FuzzyMatch struct {
+	Fr, To int // UTF-8 byte offsets
+	Text   string
+	Score  float64 // confidence in [0, 1], 1 for an exact match
+	Method string  // FuzzyEditDistance or FuzzyLCS
+}
+
+// FuzzyFindSpan finds the closest substring of the utterance to the value, nil if there is none.
+// Short values are compared by character edit distance, long values by longest common subsequence.
+// Of the same score, the substring of the length closest to the value is preferred.
+// Characters masked by # are already annotated and never matched,
+// and the digits should be the same, 100-500元 is not 100-150元.
+func FuzzyFindSpan(utterance string, slotValue string) *FuzzyMatch {
+	text, starts := runesWithOffsets(utterance)
+	value := []rune(slotValue)
+	if len(text) == 0 || len(value) == 0 {
+		return nil
+	}
+	valueDigits := digitsOf(value)
+	method := FuzzyEditDistance
+	if len(value) >= fuzzyLongValue {
+		method = FuzzyLCS
+	}
+	// windows are a half to one and a half of the value
+	minLen, maxLen := (len(value)+1)/2, len(value)+len(value)/2
+	var best *FuzzyMatch
+	bestLen := 0
+	for fr := 0; fr < len(text); fr++ {
+		for to := fr + minLen; to <= fr+maxLen && to <= len(text); to++ {
+			window := text[fr:to]
+			if containsRune(window, '#') {
+				break
+			}
+			if digitsOf(window) != valueDigits {
+				continue
+			}
+			var score float64
+			if method == FuzzyLCS {
+				// like the Dice coefficient, a longer window is not better
+				score = 2 * float64(lcsLen(window, value)) / float64(len(window)+len(value))
+			} else {
+				score = 1 - float64(editDistance(window, value))/float64(maxInt(len(window), len(value)))
+			}
+			if best == nil || score > best.Score ||
+				(score == best.Score && absInt(len(window)-len(value)) < absInt(bestLen-len(value))) {
+				best = &FuzzyMatch{Fr: starts[fr], To: starts[to], Score: score, Method: method}
+				bestLen = len(window)
+			}
+		}
+	}
+	if best == nil {
+		return nil
+	}
+	best.Text = utterance[best.Fr:best.To]
+	return best
+}
+
+// runesWithOffsets also returns the byte offset of every rune and the end
+func runesWithOffsets(s string) (runes []rune, starts []int) {
+	for i, r := range s {
+		runes = append(runes, r)
+		starts = append(starts, i)
+	}
+	return runes, append(starts, len(s))
+}
+
+func digitsOf(runes []rune) string {
+	var digits []rune
+	for _, r := range runes {
+		if r >= '0' && r <= '9' {
+			digits = append(digits, r)
+		}
+	}
+	return string(digits)
+}
+
+func containsRune(runes []rune, r rune) bool {
+	for _, c := range runes {
+		if c == r {
+			return true
+		}
+	}
+	return false
+}
+
+func editDistance(a []rune, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = minInt(prev[j-1]+cost, minInt(prev[j]+1, cur[j-1]+1))
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func lcsLen(a []rune, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			if a[i-1] == b[j-1] {
+				cur[j] = prev[j-1] + 1
+			} else {
+				cur[j] = maxInt(prev[j], cur[j-1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func absInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// SpanReview is a slot value which is not annotated, for annotators to add aliases or fix the data
+type SpanReview struct {
+	Utterance string
+	Label     string // intent.slot
+	Value     string
+	Match     *FuzzyMatch `json:",omitempty"` // the closest substring, nil if none
+}
+
+// SpanReviews are collected by ExtractSlotAnnotations
+var SpanReviews []*SpanReview
+
+func reviewSpan(utterance string, label string, value string, match *FuzzyMatch) {
+	SpanReviews = append(SpanReviews, &SpanReview{
+		Utterance: utterance,
+		Label:     label,
+		Value:     value,
+		Match:     match,
+	})
+}
+
+// WriteSpanReviews writes the collected SpanReviews to outputDir/inputFile/span_review.json,
+// and SpanConflicts to span_conflict.json
+func WriteSpanReviews(outputDir string, inputFile string) error {
+	os.MkdirAll(path.Join(outputDir, inputFile), 0755)
+	for name, v := range map[string]interface{}{"span_review": SpanReviews, "span_conflict": SpanConflicts} {
+		b, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return err
+		}
+		outputFile := path.Join(outputDir, inputFile, name+".json")
+		if err := ioutil.WriteFile(outputFile, b, 0666); err != nil {
+			return err
+		}
+	}
+	log.Println("Wrote", len(SpanReviews), "span reviews and", len(SpanConflicts), "span conflicts to", path.Join(outputDir, inputFile))
+	return nil
+}
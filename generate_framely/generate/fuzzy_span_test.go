@@ -0,0 +1,67 @@
+package generate
+
+import (
+	"testing"
+)
+
+func TestFuzzyFindSpan(t *testing.T) {
+	for _, c := range []struct {
+		utterance, slotValue, span, method string
+		minScore                           float64
+	}{
+		{"我住在京畅悦时尚酒店(原乐活酒店)，附近有什么景点", "北京畅悦时尚酒店(原乐活酒店)", "京畅悦时尚酒店(原乐活酒店)", FuzzyLCS, 0.9},
+		{"人均消费20元一下的餐馆", "20元以下", "20元一下", FuzzyEditDistance, 0.8},
+		{"人均消费50元以内的餐馆", "50元以下", "50元以内", FuzzyEditDistance, 0.8},
+		{"来一份干炸素丸了", "干炸素丸子", "干炸素丸了", FuzzyEditDistance, 0.8},
+	} {
+		match := FuzzyFindSpan(c.utterance, c.slotValue)
+		if match == nil || match.Text != c.span || match.Method != c.method || match.Score < c.minScore {
+			t.Errorf("%s in %s: expect %s, got %+v", c.slotValue, c.utterance, c.span, match)
+		}
+	}
+
+	// the digits should be the same
+	if match := FuzzyFindSpan("门票100-500元", "100-150元"); match != nil {
+		t.Errorf("expect no match, got %+v", match)
+	}
+	// annotated characters are masked by #
+	if match := FuzzyFindSpan("####", "故宫"); match != nil {
+		t.Errorf("expect no match, got %+v", match)
+	}
+	if match := FuzzyFindSpan("", "故宫"); match != nil {
+		t.Errorf("expect no match, got %+v", match)
+	}
+}
+
+func TestExtractSlotAnnotationsReview(t *testing.T) {
+	defer func(threshold float64, reviews []*SpanReview, coverage *SpanReport) {
+		FuzzyThreshold, SpanReviews, SpanCoverage = threshold, reviews, coverage
+	}(FuzzyThreshold, SpanReviews, SpanCoverage)
+	SpanReviews = nil
+	SpanCoverage = NewSpanReport()
+
+	// 干炸素丸子 has no alias, the typo is found by FuzzyFindSpan
+	FuzzyThreshold = 0.8
+	annotations := ExtractSlotAnnotations("来一份干炸素丸了", map[string]string{"推荐菜": "干炸素丸子"}, "餐馆")
+	if len(annotations) != 1 || len(SpanReviews) != 0 {
+		t.Fatalf("expect a fuzzy annotation, got %d annotations and %d reviews", len(annotations), len(SpanReviews))
+	}
+	if text, err := AnnotatedText("来一份干炸素丸了", annotations[0], OffsetBytes); err != nil || text != "干炸素丸了" {
+		t.Errorf("unexpected annotation %+v of %s, err: %v", annotations[0], text, err)
+	}
+	if coverage := SpanCoverage.Slots["餐馆.推荐菜"]; coverage == nil || coverage.Fuzzy != 1 || coverage.Total != 1 {
+		t.Errorf("expect a fuzzy span, got %+v", coverage)
+	}
+
+	// the alias 20元一下 is only for 人均消费
+	utterance := "人均消费20元一下的餐馆"
+	slots := map[string]string{"价格": "20元以下"}
+	FuzzyThreshold = 0.9
+	if annotations := ExtractSlotAnnotations(utterance, slots, "Hotel"); len(annotations) != 0 {
+		t.Errorf("expect no annotation, got %d", len(annotations))
+	}
+	if len(SpanReviews) != 1 || SpanReviews[0].Label != "Hotel.价格" || SpanReviews[0].Match == nil ||
+		SpanReviews[0].Match.Text != "20元一下" {
+		t.Errorf("unexpected reviews: %+v", SpanReviews)
+	}
+}
@@ -4,22 +4,31 @@ import (
 	"github.com/framely/sgdnlu/generate_framely/framely/p"
 	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
 	"log"
+	"sort"
 	"strings"
+	"unicode"
 )
 
 // go through dialogues to generate expressions
 // based on the generated agent
 func GenerateExpressions(allIntentIDs map[string]bool, allSlotIDs map[string]map[string]bool, dialogues []*crosswoz.Dialogue) (expressions []*p.FramelyExpression) {
-	for _, dialog := range dialogues {
+	expressions, _ = GenerateExpressionsFrom(allIntentIDs, allSlotIDs, crosswoz.NewSliceIterator(dialogues))
+	return expressions
+}
+
+// GenerateExpressionsFrom is like GenerateExpressions, but consumes the dialogues one by one
+func GenerateExpressionsFrom(allIntentIDs map[string]bool, allSlotIDs map[string]map[string]bool, it crosswoz.DialogueIterator) (expressions []*p.FramelyExpression, err error) {
+	err = crosswoz.ForEachDialogue(it, nil, func(dialog *crosswoz.Dialogue) {
+		context := NewDialogueContext()
 		for _, turn := range dialog.Turns {
-			if turn.Speaker != "usr" {
-				continue
+			if turn.Speaker == "usr" {
+				exps := ExtractExpressionsInContext(turn, context)
+				expressions = append(expressions, exps...)
 			}
-			exps := ExtractExpressions(turn)
-			expressions = append(expressions, exps...)
+			context.Observe(turn)
 		}
-	}
-	return expressions
+	})
+	return expressions, err
 }
 
 type InformedSlotValues struct {
@@ -32,6 +41,7 @@ type DialogueActsDetail struct {
 	RequestedSlots     map[string]map[string]bool     // requested slots
 	GeneralIntents     []string                       // related general intents including thank/greet/goodbye
 	InformedSlotValues map[string]*InformedSlotValues // filled slot values for each intent
+	SelectedSources    map[string]string              // source domain (源领域) of Select acts for each target intent
 }
 
 func ParseDialogueActDetail(turn *crosswoz.Message) *DialogueActsDetail {
@@ -42,7 +52,12 @@ func ParseDialogueActDetail(turn *crosswoz.Message) *DialogueActsDetail {
 	for _, act := range turn.DialogActs {
 		if act.Act == "General" {
 			detail.GeneralIntents = append(detail.GeneralIntents, act.Intent)
-		} else if act.Act == "Select" { // TODO select 有什么用？
+		} else if act.Act == "Select" {
+			// ["Select", "景点", "源领域", "餐馆"], see select_acts.go
+			if detail.SelectedSources == nil {
+				detail.SelectedSources = make(map[string]string)
+			}
+			detail.SelectedSources[act.Intent] = act.Value
 		} else if act.Act == "Request" {
 			requestedIntents[act.Intent] = true
 			if _, ok := requestedSlotsGroupedByIntent[act.Intent]; !ok {
@@ -70,12 +85,17 @@ func ParseDialogueActDetail(turn *crosswoz.Message) *DialogueActsDetail {
 	return detail
 }
 
+// ExtractExpressions extracts the expressions of a user turn without the dialogue
 func ExtractExpressions(turn *crosswoz.Message) (expressions []*p.FramelyExpression) {
+	return ExtractExpressionsInContext(turn, nil)
+}
+
+// ExtractExpressionsInContext extracts the expressions of a user turn, the contexts of the expressions
+// follow the dialogue before the turn, see DialogueContext.contextOf
+func ExtractExpressionsInContext(turn *crosswoz.Message, context *DialogueContext) (expressions []*p.FramelyExpression) {
 	detail := ParseDialogueActDetail(turn)
-	// triggering intent
-	triggeringIntent := ""
-	if len(detail.RequestedIntents) > 1 {
-		log.Fatal("Triggers multiple intents?", turn.Utterance, detail.RequestedIntents)
+	if IntentTurns != nil {
+		IntentTurns.Add(detail.RequestedIntents)
 	}
 
 	// 尝试将utterance中的空格去掉
@@ -84,108 +104,183 @@ func ExtractExpressions(turn *crosswoz.Message) (expressions []*p.FramelyExpress
 	turn.Utterance = strings.Replace(turn.Utterance, "（", "(", -1)
 	turn.Utterance = strings.Replace(turn.Utterance, "）", ")", -1)
 
-	if len(detail.RequestedIntents) == 1 {
-		triggeringIntent = detail.RequestedIntents[0]
-		//log.Println("triggering intent:", triggeringIntent)
-		exp := &p.FramelyExpression{
-			OwnerId:   triggeringIntent,
-			Utterance: turn.Utterance,
-		}
-		// 是不是真的第一次触发intent
-		if _, ok := detail.RequestedSlots[triggeringIntent]["名称"]; !ok && triggeringIntent != "地铁" && triggeringIntent != "出租" {
-			exp.Context = &p.ExpressionContext{
-				FrameId: triggeringIntent,
-			}
-		} else {
-			//exp.Context = &p.ExpressionContext{FrameId: "triggering"}
-		}
-		if slots, ok := detail.InformedSlotValues[triggeringIntent]; ok {
-			exp.Annotations = ExtractSlotAnnotations(turn.Utterance, slots.SlotValues, triggeringIntent)
-		} else {
-			//log.Println("requesting with no informed slots~~~~~~~~~~~~~~~", turn.Utterance)
+	// triggering intents, more than one are handled by MultiIntents
+	if len(detail.RequestedIntents) > 1 {
+		log.Println("~~~~triggers multiple intents:", turn.Utterance, detail.RequestedIntents, MultiIntents)
+		if MultiIntents == MultiIntentSkip {
+			return nil
 		}
-		expressions = append(expressions, exp)
 	}
+	requested := make(map[string]bool)
+	var triggered []*p.FramelyExpression
+	var hows []string
+	for _, intent := range detail.RequestedIntents {
+		requested[intent] = true
+		exp, how := triggeringExpression(turn, detail, intent, context)
+		triggered, hows = append(triggered, exp), append(hows, how)
+	}
+	if len(triggered) > 1 && MultiIntents == MultiIntentMerge {
+		merged, how := mergeExpressions(triggered, hows)
+		triggered, hows = []*p.FramelyExpression{merged}, []string{how}
+	}
+	for i, exp := range triggered {
+		countContext(exp, hows[i])
+	}
+	expressions = append(expressions, triggered...)
 
-	// non triggering intents
-	for intent, slots := range detail.InformedSlotValues {
-		booleanExpressions := BooleanExpressions(turn.Utterance, slots)
-		if len(booleanExpressions) > 0 {
-			expressions = append(expressions, booleanExpressions...)
+	// non triggering intents, in order, including the targets of Select acts
+	intentSet := make(map[string]bool)
+	for intent := range detail.InformedSlotValues {
+		intentSet[intent] = true
+	}
+	for intent := range detail.SelectedSources {
+		intentSet[intent] = true
+	}
+	for _, intent := range crosswoz.MapKeysSorted(intentSet) {
+		if slots, ok := detail.InformedSlotValues[intent]; ok {
+			booleanExpressions := BooleanExpressions(turn.Utterance, slots)
+			if len(booleanExpressions) > 0 {
+				expressions = append(expressions, booleanExpressions...)
+			}
 		}
-		if intent == triggeringIntent { // 上面已经处理过
+		if requested[intent] { // 上面已经处理过
+			continue
+		}
+		if detail.isSelectSourceOnly(intent) { // annotated as the source of the target
 			continue
 		}
 		//log.Println("informed intent:", intent)
 		exp := &p.FramelyExpression{
-			OwnerId: intent,
-			Context: &p.ExpressionContext{
-				FrameId: intent,
-			},
+			OwnerId:     intent,
 			Utterance:   turn.Utterance,
-			Annotations: ExtractSlotAnnotations(turn.Utterance, slots.SlotValues, intent),
+			Annotations: ExtractSlotAnnotations(turn.Utterance, detail.SlotValuesOf(intent), intent),
 		}
+		countContext(exp, context.setContext(exp, detail, intent, false))
 		expressions = append(expressions, exp)
 	}
 	return expressions
 }
 
-func BooleanExpressions(utterance string, values *InformedSlotValues) (expressions []*p.FramelyExpression) {
-	for slotName, slotValue := range values.SlotValues {
-		if !IsBoolean(slotName, slotValue) {
+// triggeringExpression is the expression of an intent whose slots are requested, and how its context is derived
+func triggeringExpression(turn *crosswoz.Message, detail *DialogueActsDetail, triggeringIntent string, context *DialogueContext) (*p.FramelyExpression, string) {
+	exp := &p.FramelyExpression{
+		OwnerId:   triggeringIntent,
+		Utterance: turn.Utterance,
+	}
+	how := context.setContext(exp, detail, triggeringIntent, true)
+	if slots := detail.SlotValuesOf(triggeringIntent); len(slots) > 0 {
+		exp.Annotations = ExtractSlotAnnotations(turn.Utterance, slots, triggeringIntent)
+	} else {
+		//log.Println("requesting with no informed slots~~~~~~~~~~~~~~~", turn.Utterance)
+	}
+	return exp, how
+}
+
+// find slot annotations, Fr/To are in AnnotationOffsetMode.
+// The spans of all the slots are resolved together by resolveSpans, slots without spans then try FuzzyFindSpan,
+// the annotations are sorted by offsets.
+func ExtractSlotAnnotations(utterance string, slots map[string]string, intent string) (annotations []*p.SlotAnnotation) {
+	values := make(map[string]string)
+	var candidates []*SpanCandidate
+	for _, slotName := range sortedSlotNames(slots) {
+		slotValue := slots[slotName]
+		if IsBoolean(intent, slotName) { // see BooleanExpressions
 			continue
 		}
-		exp := &p.FramelyExpression{
-			OwnerId:   "System.Boolean",
-			Utterance: utterance,
+		slotValue = normalizeSlotValue(slotValue)
+		values[slotName] = slotValue
+		candidates = append(candidates, candidateSpans(utterance, slotName, slotValue)...)
+	}
+	spans, conflicts := resolveSpans(utterance, intent, candidates)
+	for _, conflict := range conflicts {
+		log.Println("~~~~span conflict:", utterance, conflict.Dropped.Slot, utterance[conflict.Dropped.Fr:conflict.Dropped.To],
+			"overlaps", conflict.Kept.Slot, utterance[conflict.Kept.Fr:conflict.Kept.To])
+	}
+	SpanConflicts = append(SpanConflicts, conflicts...)
+
+	// the kind of a slot is the best of its spans
+	kinds := make(map[string]string)
+	// the masked utterance keeps the byte offsets
+	masked := utterance
+	for _, span := range spans {
+		kind, ok := kinds[span.Slot]
+		if ok {
+			log.Println("~~~~~~~~~~~~~~~ more than one slot values", utterance, span.Value)
+		}
+		if !ok || kind == SpanAlias {
+			kinds[span.Slot] = span.Kind
 		}
-		if slotValue == "是" {
-			exp.Label = "YES"
-		} else {
-			exp.Label = "NO"
+		annotations = appendAnnotation(annotations, utterance, span.Fr, span.To, intent+"."+span.Slot)
+		masked = masked[0:span.Fr] + strings.Repeat("#", span.To-span.Fr) + masked[span.To:]
+	}
+
+	fuzzy := false
+	for _, slotName := range sortedSlotNames(values) {
+		slotValue := values[slotName]
+		label := intent + "." + slotName
+		if kind, ok := kinds[slotName]; ok {
+			recordSpan(label, slotValue, utterance, kind)
+			continue
 		}
-		exp.Context = &p.ExpressionContext{
-			FrameId:     values.Intent,
-			AttributeId: values.Intent + "." + slotName,
+		match := FuzzyFindSpan(masked, slotValue)
+		if match != nil && match.Score >= FuzzyThreshold {
+			log.Println("~~~~fuzzy slot value: ", match.Text, " slotValue:", slotValue, " score:", match.Score)
+			annotations = appendAnnotation(annotations, utterance, match.Fr, match.To, label)
+			masked = masked[0:match.Fr] + strings.Repeat("#", match.To-match.Fr) + masked[match.To:]
+			recordSpan(label, slotValue, utterance, SpanFuzzy)
+			fuzzy = true
+			continue
 		}
-		expressions = append(expressions, exp)
+		log.Println("!!!!can not find slot value: ", masked, " slotValue:", slotValue, " slot:", slotName)
+		reviewSpan(utterance, label, slotValue, match)
+		recordSpan(label, slotValue, utterance, SpanMissing)
 	}
-	return expressions
+	if fuzzy {
+		sort.SliceStable(annotations, func(i, j int) bool {
+			return annotations[i].Fr < annotations[j].Fr
+		})
+	}
+	return annotations
 }
 
-func IsBoolean(slotName, slotValue string) bool {
-	return slotValue == "是" && slotValue != "否"
+func sortedSlotNames(slots map[string]string) []string {
+	names := make([]string, 0, len(slots))
+	for name := range slots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
 }
 
-// find slot annotations
-func ExtractSlotAnnotations(utterance string, slots map[string]string, intent string) (annotations []*p.SlotAnnotation) {
-	originUtterance := utterance
-
-	for slotName, slotValue := range slots {
-		if IsBoolean(slotName, slotValue) {
-			continue
-		}
-		slotValue = strings.Replace(slotValue, " ", "", -1)
-		slotValue = strings.Replace(slotValue, "（", "(", -1)
-		slotValue = strings.Replace(slotValue, "）", ")", -1)
-		fr, to := findSpan(utterance, slotName, slotValue)
-		cnt := 0
-		for fr != -1 {
-			cnt++
-			if cnt > 1 {
-				log.Println("~~~~~~~~~~~~~~~ more than one slot values", originUtterance, slotValue)
-			}
-			annotations = append(annotations, &p.SlotAnnotation{
-				Fr:    int32(fr),
-				To:    int32(to),
-				Label: intent + "." + slotName,
-			})
-			utterance = utterance[0:fr] + strings.Repeat("#", len(slotValue)) + utterance[(fr+len(slotValue)):]
-			fr, to = findSpan(utterance, slotName, slotValue)
-		}
-		if cnt == 0 {
-			log.Println("!!!!can not find slot value: ", utterance, " slotValue:", slotValue, " slot:", slotName)
+// normalizeSlotValue writes the value like the utterance, see ExtractExpressions
+func normalizeSlotValue(slotValue string) string {
+	slotValue = strings.Replace(slotValue, " ", "", -1)
+	slotValue = strings.Replace(slotValue, "（", "(", -1)
+	slotValue = strings.Replace(slotValue, "）", ")", -1)
+	// invisible characters like the zero width joiner of "\u200d提拉米苏"
+	return strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.Cf, r) {
+			return -1
 		}
+		return r
+	}, slotValue)
+}
+
+// NormalizeUtterance writes the utterance like the expressions, so normalized slot values are found in it
+func NormalizeUtterance(utterance string) string {
+	return normalizeSlotValue(utterance)
+}
+
+// appendAnnotation appends the span in byte offsets as an annotation in AnnotationOffsetMode
+func appendAnnotation(annotations []*p.SlotAnnotation, utterance string, fr int, to int, label string) []*p.SlotAnnotation {
+	anno := &p.SlotAnnotation{
+		Fr:    int32(fr),
+		To:    int32(to),
+		Label: label,
 	}
-	return annotations
+	if err := ConvertAnnotations(utterance, []*p.SlotAnnotation{anno}, OffsetBytes, AnnotationOffsetMode); err != nil {
+		log.Println("!!!!bad span of slot value:", err)
+		return annotations
+	}
+	return append(annotations, anno)
 }
@@ -0,0 +1,131 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+)
+
+// MultiIntentPolicy is what to do with a user turn which requests slots of more than one intent
+type MultiIntentPolicy string
+
+const (
+	// MultiIntentSplit makes an expression for each intent with the shared utterance
+	MultiIntentSplit MultiIntentPolicy = "split"
+	// MultiIntentMerge makes one expression owned by all the intents, see MultiOwnerId.
+	// No intent of the agent is a MultiOwnerId, so the expression mode writes them apart, see WriteMergedExpressions.
+	MultiIntentMerge MultiIntentPolicy = "merge"
+	// MultiIntentSkip makes no expression of the turn
+	MultiIntentSkip MultiIntentPolicy = "skip"
+)
+
+// MultiIntents is the policy of ExtractExpressions
+var MultiIntents = MultiIntentSplit
+
+func ParseMultiIntentPolicy(s string) (MultiIntentPolicy, error) {
+	switch policy := MultiIntentPolicy(s); policy {
+	case MultiIntentSplit, MultiIntentMerge, MultiIntentSkip:
+		return policy, nil
+	}
+	return "", fmt.Errorf("unknown multi-intent policy %s, expect split, merge or skip", s)
+}
+
+// MultiOwnerId is the owner of a merged expression, like 景点+酒店, the labels of its annotations tell the owner of each slot
+func MultiOwnerId(intents []string) string {
+	return strings.Join(intents, "+")
+}
+
+// SeparateMergedExpressions tells the expressions owned by a MultiOwnerId from the ones owned by an intent
+func SeparateMergedExpressions(expressions []*p.FramelyExpression) (single []*p.FramelyExpression, merged []*p.FramelyExpression) {
+	for _, exp := range expressions {
+		if strings.Contains(exp.OwnerId, "+") {
+			merged = append(merged, exp)
+		} else {
+			single = append(single, exp)
+		}
+	}
+	return single, merged
+}
+
+// WriteMergedExpressions writes the merged expressions with their annotations to multi_intent_expression.json,
+// a multi intent tagger reads the intent of each slot from the labels
+func WriteMergedExpressions(merged []*p.FramelyExpression, outputDir string, inputFile string) error {
+	b, err := json.MarshalIndent(merged, "", "  ")
+	if err != nil {
+		return err
+	}
+	os.MkdirAll(path.Join(outputDir, inputFile), 0755)
+	outputFile := path.Join(outputDir, inputFile, "multi_intent_expression.json")
+	if err := ioutil.WriteFile(outputFile, b, 0666); err != nil {
+		return err
+	}
+	log.Println("Wrote", len(merged), "merged expressions to", outputFile)
+	return nil
+}
+
+// IntentTurnReport counts user turns by the number of requested intents
+type IntentTurnReport struct {
+	Policy       MultiIntentPolicy
+	Turns        int
+	NoIntent     int
+	SingleIntent int
+	MultiIntent  int
+	Combinations map[string]int // turns of each MultiOwnerId of multiple intents
+}
+
+func NewIntentTurnReport(policy MultiIntentPolicy) *IntentTurnReport {
+	return &IntentTurnReport{Policy: policy, Combinations: make(map[string]int)}
+}
+
+// Add counts a turn of the requested intents
+func (r *IntentTurnReport) Add(intents []string) {
+	r.Turns++
+	switch len(intents) {
+	case 0:
+		r.NoIntent++
+	case 1:
+		r.SingleIntent++
+	default:
+		r.MultiIntent++
+		r.Combinations[MultiOwnerId(intents)]++
+	}
+}
+
+// IntentTurns collects the turns of ExtractExpressions, nil to not collect
+var IntentTurns *IntentTurnReport
+
+// mergeExpressions merges the expressions of the same utterance into one of all the owners, hows are how
+// their contexts are derived. The context is kept if all the expressions agree, otherwise it is ContextMerged.
+func mergeExpressions(expressions []*p.FramelyExpression, hows []string) (*p.FramelyExpression, string) {
+	var owners []string
+	merged := &p.FramelyExpression{Utterance: expressions[0].Utterance, Context: expressions[0].Context}
+	how := hows[0]
+	for i, exp := range expressions {
+		owners = append(owners, exp.OwnerId)
+		merged.Annotations = append(merged.Annotations, exp.Annotations...)
+		if !sameContext(exp.Context, merged.Context) {
+			merged.Context, how = nil, ContextMerged
+		} else if hows[i] != how {
+			how = ContextMerged
+		}
+	}
+	merged.OwnerId = MultiOwnerId(owners)
+	sort.SliceStable(merged.Annotations, func(i, j int) bool {
+		return merged.Annotations[i].Fr < merged.Annotations[j].Fr
+	})
+	return merged, how
+}
+
+func sameContext(a, b *p.ExpressionContext) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.FrameId == b.FrameId && a.AttributeId == b.AttributeId
+}
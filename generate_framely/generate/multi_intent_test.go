@@ -0,0 +1,93 @@
+package generate
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"testing"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz/crosswoztest"
+)
+
+func TestMultiIntents(t *testing.T) {
+	defer func(policy MultiIntentPolicy, report *IntentTurnReport, contexts *ContextReport) {
+		MultiIntents, IntentTurns, ExpressionContexts = policy, report, contexts
+	}(MultiIntents, IntentTurns, ExpressionContexts)
+
+	newTurn := func() *crosswoz.Message {
+		return crosswoztest.Turn("usr", "故宫的门票多少钱，北京饭店的价格呢？",
+			[4]string{"Request", "景点", "门票", ""}, [4]string{"Inform", "景点", "名称", "故宫"},
+			[4]string{"Request", "酒店", "价格", ""}, [4]string{"Inform", "酒店", "名称", "北京饭店"})
+	}
+	for _, c := range []struct {
+		policy MultiIntentPolicy
+		owners []string
+		labels [][]string
+	}{
+		{MultiIntentSplit, []string{"景点", "酒店"}, [][]string{{"景点.名称"}, {"酒店.名称"}}},
+		{MultiIntentMerge, []string{"景点+酒店"}, [][]string{{"景点.名称", "酒店.名称"}}},
+		{MultiIntentSkip, nil, nil},
+	} {
+		MultiIntents = c.policy
+		IntentTurns = NewIntentTurnReport(c.policy)
+		expressions := ExtractExpressions(newTurn())
+		if len(expressions) != len(c.owners) {
+			t.Fatalf("%s: expect %d expressions, got %d", c.policy, len(c.owners), len(expressions))
+		}
+		for i, exp := range expressions {
+			if exp.OwnerId != c.owners[i] || len(exp.Annotations) != len(c.labels[i]) {
+				t.Fatalf("%s: expect %s with %v, got %+v", c.policy, c.owners[i], c.labels[i], exp)
+			}
+			for j, anno := range exp.Annotations {
+				if anno.Label != c.labels[i][j] {
+					t.Errorf("%s: expect %v, got %s at %d", c.policy, c.labels[i], anno.Label, j)
+				}
+			}
+		}
+		if IntentTurns.Turns != 1 || IntentTurns.MultiIntent != 1 || IntentTurns.Combinations["景点+酒店"] != 1 {
+			t.Errorf("%s: unexpected report %+v", c.policy, IntentTurns)
+		}
+	}
+
+	// the heuristic contexts of 景点 and 酒店 differ, the merged expression has none
+	MultiIntents = MultiIntentMerge
+	ExpressionContexts = NewContextReport()
+	if merged := ExtractExpressions(newTurn())[0]; merged.Context != nil ||
+		ExpressionContexts.Expressions != 1 || ExpressionContexts.Intents["景点+酒店"][ContextMerged] != 1 {
+		t.Errorf("unexpected context %+v of %+v", merged.Context, ExpressionContexts)
+	}
+	// both are first triggers in a new dialogue, which the merged expression keeps
+	ExpressionContexts = NewContextReport()
+	if merged := ExtractExpressionsInContext(newTurn(), NewDialogueContext())[0]; merged.Context != nil ||
+		ExpressionContexts.Expressions != 1 || ExpressionContexts.Intents["景点+酒店"][ContextFirstTrigger] != 1 {
+		t.Errorf("unexpected context %+v of %+v", merged.Context, ExpressionContexts)
+	}
+
+	// the merged expression goes apart from the ones of intents, with its annotations
+	MultiIntents = MultiIntentSplit
+	expressions := ExtractExpressions(newTurn())
+	MultiIntents = MultiIntentMerge
+	expressions = append(expressions, ExtractExpressions(newTurn())...)
+	single, merged := SeparateMergedExpressions(expressions)
+	if len(single) != 2 || len(merged) != 1 || merged[0].OwnerId != "景点+酒店" {
+		t.Fatalf("unexpected separation %+v and %+v", single, merged)
+	}
+	outputDir := t.TempDir()
+	if err := WriteMergedExpressions(merged, outputDir, "test"); err != nil {
+		t.Fatal(err)
+	}
+	b, err := ioutil.ReadFile(path.Join(outputDir, "test", "multi_intent_expression.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var written []*p.FramelyExpression
+	if err := json.Unmarshal(b, &written); err != nil || len(written) != 1 || len(written[0].Annotations) != 2 {
+		t.Errorf("unexpected written expressions %s", b)
+	}
+
+	if _, err := ParseMultiIntentPolicy("fatal"); err == nil {
+		t.Errorf("expect an error of an unknown policy")
+	}
+}
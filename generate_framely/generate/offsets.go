@@ -0,0 +1,127 @@
+package generate
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+)
+
+// OffsetMode is the unit of SlotAnnotation.Fr/To
+type OffsetMode string
+
+const (
+	// OffsetBytes are UTF-8 byte offsets, what strings.Index returns and what framely expects
+	OffsetBytes OffsetMode = "bytes"
+	// OffsetRunes are character offsets, for char-level taggers in Go or Python 3
+	OffsetRunes OffsetMode = "runes"
+	// OffsetUTF16 are UTF-16 code unit offsets, for Java and JavaScript
+	OffsetUTF16 OffsetMode = "utf16"
+)
+
+// AnnotationOffsetMode is the unit of the annotations made by ExtractSlotAnnotations
+var AnnotationOffsetMode = OffsetBytes
+
+func ParseOffsetMode(s string) (OffsetMode, error) {
+	switch mode := OffsetMode(s); mode {
+	case OffsetBytes, OffsetRunes, OffsetUTF16:
+		return mode, nil
+	}
+	return "", fmt.Errorf("unknown offset mode %s, expect bytes, runes or utf16", s)
+}
+
+// ConvertOffset converts an offset in s from one mode to another,
+// the offset should be on a character boundary and at most the length of s
+func ConvertOffset(s string, offset int, from OffsetMode, to OffsetMode) (int, error) {
+	byteOffset, err := toByteOffset(s, offset, from)
+	if err != nil {
+		return -1, err
+	}
+	if to == OffsetBytes {
+		return byteOffset, nil
+	}
+	converted := 0
+	for _, r := range s[:byteOffset] {
+		converted += unitLen(r, to)
+	}
+	return converted, nil
+}
+
+func toByteOffset(s string, offset int, mode OffsetMode) (int, error) {
+	if offset < 0 {
+		return -1, fmt.Errorf("negative offset %d", offset)
+	}
+	if mode == OffsetBytes {
+		if offset > len(s) || (offset < len(s) && !utf8.RuneStart(s[offset])) {
+			return -1, fmt.Errorf("byte offset %d is not a character boundary of %s", offset, s)
+		}
+		return offset, nil
+	}
+	units := 0
+	for i, r := range s {
+		if units == offset {
+			return i, nil
+		}
+		units += unitLen(r, mode)
+		if units > offset {
+			return -1, fmt.Errorf("%s offset %d is in the middle of %q in %s", mode, offset, r, s)
+		}
+	}
+	if units == offset {
+		return len(s), nil
+	}
+	return -1, fmt.Errorf("%s offset %d is beyond %s", mode, offset, s)
+}
+
+// unitLen is the length of the rune in runes or UTF-16 code units
+func unitLen(r rune, mode OffsetMode) int {
+	if mode == OffsetUTF16 && r >= 0x10000 {
+		return 2
+	}
+	return 1
+}
+
+// ConvertAnnotations converts Fr/To of the annotations of the utterance in place
+func ConvertAnnotations(utterance string, annotations []*p.SlotAnnotation, from OffsetMode, to OffsetMode) error {
+	if from == to {
+		return nil
+	}
+	for _, anno := range annotations {
+		fr, err := ConvertOffset(utterance, int(anno.Fr), from, to)
+		if err != nil {
+			return err
+		}
+		end, err := ConvertOffset(utterance, int(anno.To), from, to)
+		if err != nil {
+			return err
+		}
+		anno.Fr, anno.To = int32(fr), int32(end)
+	}
+	return nil
+}
+
+// ConvertExpressionOffsets converts the annotations of the expressions in place
+func ConvertExpressionOffsets(expressions []*p.FramelyExpression, from OffsetMode, to OffsetMode) error {
+	for _, exp := range expressions {
+		if err := ConvertAnnotations(exp.Utterance, exp.Annotations, from, to); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AnnotatedText is the substring of the utterance covered by the annotation
+func AnnotatedText(utterance string, anno *p.SlotAnnotation, mode OffsetMode) (string, error) {
+	fr, err := toByteOffset(utterance, int(anno.Fr), mode)
+	if err != nil {
+		return "", err
+	}
+	to, err := toByteOffset(utterance, int(anno.To), mode)
+	if err != nil {
+		return "", err
+	}
+	if fr > to {
+		return "", fmt.Errorf("bad annotation %d-%d of %s", anno.Fr, anno.To, utterance)
+	}
+	return utterance[fr:to], nil
+}
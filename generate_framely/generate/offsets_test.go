@@ -0,0 +1,152 @@
+package generate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+func TestConvertOffset(t *testing.T) {
+	s := "a故宫😀b"
+	// bytes, runes and utf16 offsets of every character boundary
+	boundaries := [][3]int{{0, 0, 0}, {1, 1, 1}, {4, 2, 2}, {7, 3, 3}, {11, 4, 5}, {12, 5, 6}}
+	modes := []OffsetMode{OffsetBytes, OffsetRunes, OffsetUTF16}
+	for _, b := range boundaries {
+		for i, from := range modes {
+			for j, to := range modes {
+				if converted, err := ConvertOffset(s, b[i], from, to); err != nil || converted != b[j] {
+					t.Errorf("%s %d -> %s: expect %d, got %d, err: %v", from, b[i], to, b[j], converted, err)
+				}
+			}
+		}
+	}
+	for _, c := range []struct {
+		offset int
+		mode   OffsetMode
+	}{{2, OffsetBytes}, {13, OffsetBytes}, {4, OffsetUTF16}, {6, OffsetRunes}, {-1, OffsetRunes}} {
+		if _, err := ConvertOffset(s, c.offset, c.mode, OffsetRunes); err == nil {
+			t.Errorf("expect an error for %s offset %d", c.mode, c.offset)
+		}
+	}
+	if _, err := ParseOffsetMode("chars"); err == nil {
+		t.Error("expect an unknown offset mode")
+	}
+}
+
+// real utterances of demo10034.json with the expected annotations in every offset mode,
+// Chinese characters are one rune and one UTF-16 unit of three bytes
+func TestAnnotationOffsets(t *testing.T) {
+	defer func() { AnnotationOffsetMode = OffsetBytes }()
+	type span struct {
+		label, text         string
+		bytes, runes, utf16 [2]int32
+	}
+	for _, c := range []struct {
+		utterance string
+		intent    string
+		slots     map[string]string
+		spans     []span
+	}{
+		{"哪家店的人均消费在100-150元的呢？评分要是4.5分以上就更好了。", "餐馆", map[string]string{"人均消费": "100-150元", "评分": "4.5分以上"},
+			[]span{{"餐馆.人均消费", "100-150元", [2]int32{27, 37}, [2]int32{9, 17}, [2]int32{9, 17}},
+				{"餐馆.评分", "4.5分以上", [2]int32{58, 70}, [2]int32{24, 30}, [2]int32{24, 30}}}},
+		// 不免费 is said as 不免票
+		{"好吧，那我去别的地方玩吧！给我找一个评分是5分的，不免票的景点。", "景点", map[string]string{"评分": "5分", "门票": "不免费"},
+			[]span{{"景点.评分", "5分", [2]int32{63, 67}, [2]int32{21, 23}, [2]int32{21, 23}},
+				{"景点.门票", "不免票", [2]int32{73, 82}, [2]int32{25, 28}, [2]int32{25, 28}}}},
+		{"那就奢侈一回。大渔铁板烧(蓝色港湾店)在什么地方？", "餐馆", map[string]string{"名称": "大渔铁板烧(蓝色港湾店)"},
+			[]span{{"餐馆.名称", "大渔铁板烧(蓝色港湾店)", [2]int32{21, 53}, [2]int32{7, 19}, [2]int32{7, 19}}}},
+		{"我准备乘出租车从北京古代建筑博物馆到北京丽晶酒店，帮我叫个车，顺便告诉我一下车牌和车型。", "出租",
+			map[string]string{"出发地": "北京古代建筑博物馆", "目的地": "北京丽晶酒店"},
+			[]span{{"出租.出发地", "北京古代建筑博物馆", [2]int32{24, 51}, [2]int32{8, 17}, [2]int32{8, 17}},
+				{"出租.目的地", "北京丽晶酒店", [2]int32{54, 72}, [2]int32{18, 24}, [2]int32{18, 24}}}},
+	} {
+		for _, mode := range []OffsetMode{OffsetBytes, OffsetRunes, OffsetUTF16} {
+			AnnotationOffsetMode = mode
+			annotations := ExtractSlotAnnotations(c.utterance, c.slots, c.intent)
+			if len(annotations) != len(c.spans) {
+				t.Fatalf("%s %s: expect %d annotations, got %d", mode, c.utterance, len(c.spans), len(annotations))
+			}
+			for i, expect := range c.spans {
+				offsets := map[OffsetMode][2]int32{OffsetBytes: expect.bytes, OffsetRunes: expect.runes, OffsetUTF16: expect.utf16}[mode]
+				anno := annotations[i]
+				text, err := AnnotatedText(c.utterance, anno, mode)
+				if anno.Label != expect.label || anno.Fr != offsets[0] || anno.To != offsets[1] || err != nil || text != expect.text {
+					t.Errorf("%s %s: expect %s %s at %v, got %+v %s, err: %v", mode, c.utterance, expect.label, expect.text, offsets, anno, text, err)
+				}
+			}
+		}
+	}
+}
+
+// every annotation of the demo dialogues should cover the slot value or one of its candidate spans in every
+// offset mode, a supplement of TestAnnotationOffsets. Fuzzy annotations are told by SpanCoverage and only round tripped
+func TestAnnotationRoundTrip(t *testing.T) {
+	defer func(coverage *SpanReport) {
+		AnnotationOffsetMode, SpanCoverage = OffsetBytes, coverage
+	}(SpanCoverage)
+	var dialogues []*crosswoz.Dialogue
+	for _, fileName := range []string{"../../data/crosswoz/demo2303.json", "../../data/crosswoz/demo10034.json"} {
+		loaded, err := crosswoz.LoadDialogues(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dialogues = append(dialogues, loaded...)
+	}
+	for _, mode := range []OffsetMode{OffsetBytes, OffsetRunes, OffsetUTF16} {
+		AnnotationOffsetMode = mode
+		checked, fuzzy := 0, 0
+		for _, dialogue := range dialogues {
+			for _, turn := range dialogue.Turns {
+				if turn.Speaker != "usr" {
+					continue
+				}
+				detail := ParseDialogueActDetail(turn)
+				SpanCoverage = NewSpanReport()
+				for _, exp := range ExtractExpressions(turn) {
+					for _, anno := range exp.Annotations {
+						text, err := AnnotatedText(exp.Utterance, anno, mode)
+						if err != nil {
+							t.Fatal(err)
+						}
+						intent, slotName := anno.Label[:strings.Index(anno.Label, ".")], anno.Label[strings.Index(anno.Label, ".")+1:]
+						value := normalizeSlotValue(detail.SlotValuesOf(intent)[slotName])
+						if coverage, ok := SpanCoverage.Slots[anno.Label]; ok && coverage.Fuzzy > 0 {
+							fuzzy++
+						} else if !isCandidateText(exp.Utterance, slotName, value, text) {
+							t.Errorf("%s: %s is annotated as %s in %s", mode, text, value, exp.Utterance)
+						}
+						// to bytes and back
+						roundTrip := &p.SlotAnnotation{Fr: anno.Fr, To: anno.To, Label: anno.Label}
+						if err := ConvertAnnotations(exp.Utterance, []*p.SlotAnnotation{roundTrip}, mode, OffsetBytes); err != nil {
+							t.Fatal(err)
+						}
+						if err := ConvertAnnotations(exp.Utterance, []*p.SlotAnnotation{roundTrip}, OffsetBytes, mode); err != nil || roundTrip.Fr != anno.Fr || roundTrip.To != anno.To {
+							t.Errorf("%s: round trip of %+v: %+v, err: %v", mode, anno, roundTrip, err)
+						}
+						checked++
+					}
+				}
+			}
+		}
+		if checked == fuzzy {
+			t.Errorf("%s: no annotation is checked against its value", mode)
+		}
+		t.Logf("%s: %d annotations, %d fuzzy", mode, checked, fuzzy)
+	}
+}
+
+// isCandidateText tells whether the annotated text is the value or the text of one of its candidate spans
+func isCandidateText(utterance string, slotName string, value string, text string) bool {
+	if text == value {
+		return true
+	}
+	for _, span := range candidateSpans(utterance, slotName, value) {
+		if utterance[span.Fr:span.To] == text {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,197 @@
+package generate
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+// DefaultTopResponses is how many templates of each dialog act combination are attached to an intent
+const DefaultTopResponses = 3
+
+// MinResponseCount is the least count of a mined template to be used, a template seen once is mostly noise.
+// It is the only count threshold of the miners of sys turns.
+var MinResponseCount = 2
+
+// ResponseTemplate is a delexicalized sys utterance, like $名称的门票是$门票。
+type ResponseTemplate struct {
+	Template string
+	Count    int
+	Example  string // the first utterance of the template
+}
+
+// ResponseGroup are the templates of sys turns of the same dialog act combination
+type ResponseGroup struct {
+	Acts       string // like Inform+景点+门票,Inform+景点+名称
+	Intent     string // the only intent of the acts besides General, "" if none or more than one
+	Turns      int
+	Incomplete int                          // turns with values not found in the utterance
+	Templates  map[string]*ResponseTemplate // by template
+}
+
+// Top are the n most frequent templates, the shorter first of the same count
+func (g *ResponseGroup) Top(n int) []*ResponseTemplate {
+	return topTemplates(g.Templates, n)
+}
+
+func topTemplates(byTemplate map[string]*ResponseTemplate, n int) []*ResponseTemplate {
+	var templates []*ResponseTemplate
+	for _, template := range byTemplate {
+		templates = append(templates, template)
+	}
+	sort.Slice(templates, func(i, j int) bool {
+		a, b := templates[i], templates[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		if len(a.Template) != len(b.Template) {
+			return len(a.Template) < len(b.Template)
+		}
+		return a.Template < b.Template
+	})
+	if len(templates) > n {
+		templates = templates[:n]
+	}
+	return templates
+}
+
+// ResponseMiner mines response templates from sys turns
+type ResponseMiner struct {
+	Turns  int
+	Groups map[string]*ResponseGroup // by Acts
+}
+
+func NewResponseMiner() *ResponseMiner {
+	return &ResponseMiner{Groups: make(map[string]*ResponseGroup)}
+}
+
+// MineResponses mines the sys turns of the dialogues
+func MineResponses(it crosswoz.DialogueIterator) (*ResponseMiner, error) {
+	miner := NewResponseMiner()
+	err := crosswoz.ForEachDialogue(it, nil, func(dialog *crosswoz.Dialogue) {
+		for _, turn := range dialog.Turns {
+			if turn.Speaker == "sys" {
+				miner.Add(turn)
+			}
+		}
+	})
+	return miner, err
+}
+
+// ActCombination is the sorted dialog acts of the turn without values, and its only intent besides General
+func ActCombination(turn *crosswoz.Message) (acts string, intent string) {
+	keys := make(map[string]bool)
+	intents := make(map[string]bool)
+	for _, act := range turn.DialogActs {
+		key := act.Act + "+" + act.Intent
+		if act.Slot != "" && act.Slot != "none" {
+			key += "+" + act.Slot
+		}
+		keys[key] = true
+		if act.Act != "General" {
+			intents[act.Intent] = true
+		}
+	}
+	if len(intents) == 1 {
+		intent = crosswoz.MapKeysSorted(intents)[0]
+	}
+	return strings.Join(crosswoz.MapKeysSorted(keys), ","), intent
+}
+
+// Add mines a sys turn
+func (m *ResponseMiner) Add(turn *crosswoz.Message) {
+	acts, intent := ActCombination(turn)
+	if acts == "" {
+		return
+	}
+	m.Turns++
+	group, ok := m.Groups[acts]
+	if !ok {
+		group = &ResponseGroup{Acts: acts, Intent: intent, Templates: make(map[string]*ResponseTemplate)}
+		m.Groups[acts] = group
+	}
+	group.Turns++
+	utterance := normalizeSlotValue(turn.Utterance)
+	template, complete := Delexicalize(utterance, turn.DialogActs)
+	if !complete {
+		group.Incomplete++
+		return
+	}
+	addTemplate(group.Templates, template, utterance)
+}
+
+// addTemplate counts the template, the first utterance is its example
+func addTemplate(templates map[string]*ResponseTemplate, template string, utterance string) {
+	if t, ok := templates[template]; ok {
+		t.Count++
+	} else {
+		templates[template] = &ResponseTemplate{Template: template, Count: 1, Example: utterance}
+	}
+}
+
+// Delexicalize replaces the values of the acts in the utterance with $slot, like 故宫的门票是60元 -> $名称的门票是$门票.
+// The spans are found and resolved like ExtractSlotAnnotations, without fuzzy matching.
+// complete is false if a value is not found, values of boolean slots are not in utterances.
+func Delexicalize(utterance string, acts []*crosswoz.DialogAct) (template string, complete bool) {
+	var candidates []*SpanCandidate
+	values := make(map[string]bool)
+	for _, act := range acts {
+		if act.Value == "" || act.Value == "none" || IsBoolean(act.Intent, act.Slot) {
+			continue
+		}
+		value := normalizeSlotValue(act.Value)
+		label := act.Intent + "." + act.Slot
+		values[label+"="+value] = true
+		for _, candidate := range candidateSpans(utterance, act.Slot, value) {
+			candidate.Slot = label
+			candidates = append(candidates, candidate)
+		}
+	}
+	spans, _ := resolveSpans(utterance, "", candidates)
+	for _, span := range spans {
+		delete(values, span.Slot+"="+span.Value)
+	}
+	// from the end to keep the offsets
+	template = utterance
+	for i := len(spans) - 1; i >= 0; i-- {
+		span := spans[i]
+		template = template[:span.Fr] + "$" + span.Slot[strings.Index(span.Slot, ".")+1:] + template[span.To:]
+	}
+	return template, len(values) == 0
+}
+
+// Responses are the top templates of the combinations of the intent, the most frequent combinations first,
+// templates less than MinResponseCount are not responses
+func (m *ResponseMiner) Responses(intent string, top int) (responses []string) {
+	var groups []*ResponseGroup
+	for _, group := range m.Groups {
+		if group.Intent == intent {
+			groups = append(groups, group)
+		}
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Turns != groups[j].Turns {
+			return groups[i].Turns > groups[j].Turns
+		}
+		return groups[i].Acts < groups[j].Acts
+	})
+	seen := make(map[string]bool)
+	for _, group := range groups {
+		for _, template := range group.Top(top) {
+			if template.Count >= MinResponseCount && !seen[template.Template] {
+				seen[template.Template] = true
+				responses = append(responses, template.Template)
+			}
+		}
+	}
+	return responses
+}
+
+// Attach sets the responses of the intents by their MetaId
+func (m *ResponseMiner) Attach(intents []*p.IntentMeta, top int) {
+	for _, intent := range intents {
+		intent.Responses = m.Responses(intent.MetaId, top)
+	}
+}
@@ -0,0 +1,81 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz/crosswoztest"
+)
+
+func TestDelexicalize(t *testing.T) {
+	acts := crosswoztest.Acts
+	for _, c := range []struct {
+		utterance string
+		acts      []*crosswoz.DialogAct
+		template  string
+		complete  bool
+	}{
+		{"为您推荐故宫、天坛。", acts([4]string{"Recommend", "景点", "名称", "故宫"}, [4]string{"Recommend", "景点", "名称", "天坛"}),
+			"为您推荐$名称、$名称。", true},
+		{"故宫的门票是60元。", acts([4]string{"Inform", "景点", "门票", "60元"}, [4]string{"Inform", "景点", "名称", "故宫"}),
+			"$名称的门票是$门票。", true},
+		{"车型#CX，车牌#CP。", acts([4]string{"Inform", "出租", "车型", "#CX"}, [4]string{"Inform", "出租", "车牌", "#CP"}),
+			"车型$车型，车牌$车牌。", true},
+		// boolean values are not in the utterance
+		{"有健身房。", acts([4]string{"Inform", "酒店", "酒店设施-健身房", "是"}), "有健身房。", true},
+		{"没有符合条件的景点。", acts([4]string{"NoOffer", "景点", "none", "none"}), "没有符合条件的景点。", true},
+		{"门票是免费的。", acts([4]string{"Inform", "景点", "门票", "60元"}), "门票是免费的。", false},
+	} {
+		if template, complete := Delexicalize(c.utterance, c.acts); template != c.template || complete != c.complete {
+			t.Errorf("%s: expect %s %v, got %s %v", c.utterance, c.template, c.complete, template, complete)
+		}
+	}
+}
+
+func TestMineResponses(t *testing.T) {
+	reader, err := crosswoz.OpenDialogueReader("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	miner, err := MineResponses(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if miner.Turns == 0 || len(miner.Groups) == 0 {
+		t.Fatalf("no sys turns are mined")
+	}
+	for acts, group := range miner.Groups {
+		if group.Acts != acts || group.Turns < group.Incomplete {
+			t.Errorf("unexpected group %+v", group)
+		}
+		for template, tmpl := range group.Templates {
+			if tmpl.Template != template || tmpl.Count == 0 || tmpl.Example == "" {
+				t.Errorf("unexpected template %+v", tmpl)
+			}
+		}
+	}
+
+	// responses of a single turn are not attached unless MinResponseCount is 1
+	defer func(min int) { MinResponseCount = min }(MinResponseCount)
+	MinResponseCount = 1
+	intents := []*p.IntentMeta{{MetaId: "景点"}, {MetaId: "酒店"}, {MetaId: "天气"}}
+	miner.Attach(intents, DefaultTopResponses)
+	if len(intents[0].Responses) == 0 || len(intents[1].Responses) == 0 || len(intents[2].Responses) != 0 {
+		t.Errorf("unexpected responses: %v, %v, %v", intents[0].Responses, intents[1].Responses, intents[2].Responses)
+	}
+	seen := make(map[string]bool)
+	for _, response := range intents[0].Responses {
+		if seen[response] {
+			t.Errorf("duplicated response %s", response)
+		}
+		seen[response] = true
+	}
+
+	turn := crosswoztest.Turn("sys", "", [4]string{"Inform", "景点", "门票", "60元"},
+		[4]string{"General", "reqmore", "none", "none"}, [4]string{"Inform", "景点", "名称", "故宫"})
+	if acts, intent := ActCombination(turn); acts != "General+reqmore,Inform+景点+名称,Inform+景点+门票" || intent != "景点" {
+		t.Errorf("unexpected combination %s of %s", acts, intent)
+	}
+}
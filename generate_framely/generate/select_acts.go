@@ -0,0 +1,84 @@
+package generate
+
+import (
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+)
+
+// A Select act like ["Select", "景点", "源领域", "餐馆"] looks for 景点 among the 周边景点 of a 餐馆,
+// the 餐馆 is the source entity and 餐馆 is its domain (源领域).
+
+// SelectSourceDomains are the domains with 周边 lists, which can be the source of a Select act
+var SelectSourceDomains = []string{"景点", "酒店", "餐馆"}
+
+// SelectSourceSlot is the slot of the target intent for the name of the source entity, like 源餐馆
+func SelectSourceSlot(sourceDomain string) string {
+	return "源" + sourceDomain
+}
+
+// selectSourceSlots are the slots of the source entities of a searchable domain
+func selectSourceSlots(intentID string) (slots []*p.FramelySlot) {
+	for _, source := range SelectSourceDomains {
+		slots = append(slots, &p.FramelySlot{
+			AttributeId:  intentID + "." + SelectSourceSlot(source),
+			Name:         SelectSourceSlot(source),
+			TypeId:       entityID(source, "名称"),
+			AllowAskSlot: false,
+		})
+	}
+	return slots
+}
+
+// selectContext is the context of an expression which selects the target among the 周边 of the source entity,
+// the frame of the source domain with its 周边 list of the target
+func selectContext(target string, source string) *p.ExpressionContext {
+	return &p.ExpressionContext{
+		FrameId:     source,
+		AttributeId: source + ".周边" + target,
+	}
+}
+
+// selectSourceName is the name of the source entity of the intent informed in the same turn, "" if none
+func (detail *DialogueActsDetail) selectSourceName(intent string) string {
+	source, ok := detail.SelectedSources[intent]
+	if !ok {
+		return ""
+	}
+	if values, ok := detail.InformedSlotValues[source]; ok {
+		return values.SlotValues["名称"]
+	}
+	return ""
+}
+
+// SlotValuesOf are the informed slot values of the intent to annotate.
+// The name of the source entity of a Select act goes to the source slot, like 源餐馆,
+// also for the same domain, 这家餐馆周边的餐馆 is not a 名称 of the target.
+func (detail *DialogueActsDetail) SlotValuesOf(intent string) map[string]string {
+	values := make(map[string]string)
+	if informed, ok := detail.InformedSlotValues[intent]; ok {
+		for slot, value := range informed.SlotValues {
+			values[slot] = value
+		}
+	}
+	if name := detail.selectSourceName(intent); name != "" {
+		source := detail.SelectedSources[intent]
+		if source == intent {
+			delete(values, "名称")
+		}
+		values[SelectSourceSlot(source)] = name
+	}
+	return values
+}
+
+// isSelectSourceOnly if the intent only informs the name of a source entity of another intent
+func (detail *DialogueActsDetail) isSelectSourceOnly(intent string) bool {
+	informed, ok := detail.InformedSlotValues[intent]
+	if !ok || len(informed.SlotValues) != 1 {
+		return false
+	}
+	for target, source := range detail.SelectedSources {
+		if source == intent && target != intent && detail.selectSourceName(target) != "" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package generate
+
+import (
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz/crosswoztest"
+)
+
+func TestSelectExpressions(t *testing.T) {
+	for _, c := range []struct {
+		utterance string
+		acts      [][4]string
+		owner     string
+		context   string   // FrameId.AttributeId of the context
+		spans     []string // text and label of the annotations in order
+	}{
+		// the source entity is not named
+		{"哦，我想在这些附近景点里找一个4.5分以上的，有吗？",
+			[][4]string{{"Inform", "景点", "评分", "4.5分以上"}, {"Select", "景点", "源领域", "餐馆"}},
+			"景点", "餐馆.餐馆.周边景点", []string{"4.5分以上", "景点.评分"}},
+		// the source entity is named, there is no expression of 酒店
+		{"帮我在北京华尔顿酒店周边找个4.5分以上的景点",
+			[][4]string{{"Inform", "景点", "评分", "4.5分以上"}, {"Inform", "酒店", "名称", "北京华尔顿酒店"}, {"Select", "景点", "源领域", "酒店"}},
+			"景点", "酒店.酒店.周边景点", []string{"北京华尔顿酒店", "景点.源酒店", "4.5分以上", "景点.评分"}},
+		// of the same domain, the name is the source
+		{"全聚德周边有好吃烤鸭的餐馆吗",
+			[][4]string{{"Inform", "餐馆", "推荐菜", "烤鸭"}, {"Inform", "餐馆", "名称", "全聚德"}, {"Select", "餐馆", "源领域", "餐馆"}},
+			"餐馆", "餐馆.餐馆.周边餐馆", []string{"全聚德", "餐馆.源餐馆", "烤鸭", "餐馆.推荐菜"}},
+		// only a Select act
+		{"就在这些景点里选吧",
+			[][4]string{{"Select", "景点", "源领域", "景点"}},
+			"景点", "景点.景点.周边景点", nil},
+	} {
+		turn := crosswoztest.Turn("usr", c.utterance, c.acts...)
+		expressions := ExtractExpressions(turn)
+		if len(expressions) != 1 {
+			t.Fatalf("%s: expect 1 expression, got %d", c.utterance, len(expressions))
+		}
+		exp := expressions[0]
+		if exp.OwnerId != c.owner || exp.Context == nil || exp.Context.FrameId+"."+exp.Context.AttributeId != c.context {
+			t.Errorf("%s: expect %s in context %s, got %s in %+v", c.utterance, c.owner, c.context, exp.OwnerId, exp.Context)
+		}
+		var spans []string
+		for _, anno := range exp.Annotations {
+			spans = append(spans, c.utterance[anno.Fr:anno.To], anno.Label)
+		}
+		if len(spans) != len(c.spans) {
+			t.Fatalf("%s: expect %v, got %v", c.utterance, c.spans, spans)
+		}
+		for i := range spans {
+			if spans[i] != c.spans[i] {
+				t.Errorf("%s: expect %v, got %v", c.utterance, c.spans, spans)
+				break
+			}
+		}
+	}
+}
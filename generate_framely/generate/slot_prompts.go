@@ -0,0 +1,388 @@
+package generate
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+var (
+	// sentences of an utterance with their terminators, a question ends with ？
+	regSentence = regexp.MustCompile(`[^。！!？?；;]+[。！!？?；;]*`)
+	// questions offering more help like 还需要其他信息么？ are reqmore, not prompts of a slot
+	regReqmore  = regexp.MustCompile(`(其他|其它|别的)的?(信息|问题|要求|需求|需要|帮助)|还(有|需要)什么|什么(需要|帮助|问题|需求)`)
+	regAskPrice = regexp.MustCompile(`多少钱|什么价位|价位|价钱|预算`)
+	// question words asking for a slot by the slot name, besides the slot name itself
+	regAskSlot = map[string]*regexp.Regexp{
+		"名称":   regexp.MustCompile(`哪(家|个|一家|一个|所)|什么名字|叫什么`),
+		"出发地":  regexp.MustCompile(`(从|在)哪|出发`),
+		"目的地":  regexp.MustCompile(`(到|去)哪|到达`),
+		"价格":   regAskPrice,
+		"门票":   regAskPrice,
+		"人均消费": regAskPrice,
+		"评分":   regexp.MustCompile(`几分|多少分`),
+		"游玩时间": regexp.MustCompile(`多久|多长时间|玩多长`),
+		"酒店类型": regexp.MustCompile(`什么类型|哪种|哪类`),
+		"推荐菜":  regexp.MustCompile(`什么菜|吃什么|哪(道|个)菜`),
+	}
+)
+
+// PromptGroup are the templates of the prompts of a slot
+type PromptGroup struct {
+	Slot      string // like 酒店.价格
+	Turns     int
+	Templates map[string]*ResponseTemplate
+}
+
+// Prompts are the top templates seen at least MinResponseCount times, nil without a group
+func (g *PromptGroup) Prompts(top int) (prompts []string) {
+	if g == nil {
+		return nil
+	}
+	for _, template := range topTemplates(g.Templates, top) {
+		if template.Count >= MinResponseCount {
+			prompts = append(prompts, template.Template)
+		}
+	}
+	return prompts
+}
+
+// PromptMiner mines slot prompts from sys turns, by the attribute id of the slot.
+// CrossWOZ has no sys Request acts, a sys question asks for the slots the user informs next,
+// and a question holding a single value confirms it.
+type PromptMiner struct {
+	Turns      int
+	AskSlot    map[string]*PromptGroup // sys questions answered by informing the slot
+	MultiValue map[string]*PromptGroup // sys questions answered by informing the slot again
+	Confirm    map[string]*PromptGroup // sys question sentences holding a single value of the slot, like 推荐您去$名称，您看可以吗？
+}
+
+func NewPromptMiner() *PromptMiner {
+	return &PromptMiner{
+		AskSlot:    make(map[string]*PromptGroup),
+		MultiValue: make(map[string]*PromptGroup),
+		Confirm:    make(map[string]*PromptGroup),
+	}
+}
+
+// MinePrompts mines the sys turns of the dialogues
+func MinePrompts(it crosswoz.DialogueIterator) (*PromptMiner, error) {
+	miner := NewPromptMiner()
+	err := crosswoz.ForEachDialogue(it, nil, miner.AddDialogue)
+	return miner, err
+}
+
+// AddDialogue mines the sys turns of a dialogue, with the next turn and the slots informed by the user so far
+func (m *PromptMiner) AddDialogue(dialogue *crosswoz.Dialogue) {
+	informed := make(map[string]bool)
+	for i, turn := range dialogue.Turns {
+		if turn.Speaker == "sys" {
+			var next *crosswoz.Message
+			if i+1 < len(dialogue.Turns) {
+				next = dialogue.Turns[i+1]
+			}
+			m.Add(turn, next, informed)
+			continue
+		}
+		for _, act := range turn.DialogActs {
+			if act.Act == "Inform" {
+				informed[act.Intent+"."+act.Slot] = true
+			}
+		}
+	}
+}
+
+// Add mines a sys turn followed by the next turn, an asked slot is a multi value prompt if the user has informed it
+func (m *PromptMiner) Add(turn *crosswoz.Message, next *crosswoz.Message, informed map[string]bool) {
+	m.Turns++
+	utterance := normalizeSlotValue(turn.Utterance)
+	sentences := regSentence.FindAllString(utterance, -1)
+	if len(sentences) == 0 {
+		return
+	}
+	question := sentences[len(sentences)-1]
+	slots, template := askedSlots(turn, next, question)
+	for _, slot := range slots {
+		if informed[slot] {
+			addPrompt(m.MultiValue, slot, template, utterance)
+		} else {
+			addPrompt(m.AskSlot, slot, template, utterance)
+		}
+	}
+	for _, sentence := range sentences {
+		if act, template := confirmedAct(turn, sentence); act != nil {
+			addPrompt(m.Confirm, act.Intent+"."+act.Slot, template, utterance)
+		}
+	}
+}
+
+// isQuestion is a sentence ending with a question mark, not offering more help
+func isQuestion(sentence string) bool {
+	return (strings.HasSuffix(sentence, "？") || strings.HasSuffix(sentence, "?")) && !regReqmore.MatchString(sentence)
+}
+
+// hasValue is an act of a value which can be a placeholder
+func hasValue(act *crosswoz.DialogAct) bool {
+	return act.Value != "" && act.Value != "none" && act.Value != "无" && !IsBoolean(act.Intent, act.Slot)
+}
+
+// askedSlots are the slots the user informs after a question of the sys and asked by it, see askingClause.
+// Booleans are not asked.
+func askedSlots(turn *crosswoz.Message, next *crosswoz.Message, question string) (slots []string, template string) {
+	if next == nil || next.Speaker != "usr" {
+		return nil, ""
+	}
+	if template = askingClause(turn, question); template == "" {
+		return nil, ""
+	}
+	seen := make(map[string]bool)
+	for _, act := range next.DialogActs {
+		slot := act.Intent + "." + act.Slot
+		if act.Act == "Inform" && hasValue(act) && !seen[slot] && asksFor(template, act.Slot) {
+			seen[slot] = true
+			slots = append(slots, slot)
+		}
+	}
+	if len(slots) == 0 {
+		return nil, ""
+	}
+	return slots, template
+}
+
+// askingClause is the template of the clause asking in a question of the sys turn, "" if it asks for no slot.
+// The sys mostly informs while it asks, the informed values are delexicalized out of the question and
+// the clause asking is the template, like 请问要到哪里？ of $出发地附近的地铁站是$出发地附近地铁站，请问要到哪里？.
+// A question holding recommended values, or any value in the clause asking, is a confirmation or a choice
+// like $名称和$名称你想去哪个？, and a slot is asked only if the template mentions it or its question words.
+func askingClause(turn *crosswoz.Message, question string) string {
+	if !isQuestion(question) {
+		return ""
+	}
+	if confirmed, _ := confirmedAct(turn, question); confirmed != nil {
+		return ""
+	}
+	var values []*crosswoz.DialogAct
+	for _, act := range turn.DialogActs {
+		if act.Act == "General" && act.Intent == "reqmore" {
+			return ""
+		}
+		if act.Act != "Inform" && act.Act != "Recommend" || !hasValue(act) {
+			continue
+		}
+		if _, found := Delexicalize(question, []*crosswoz.DialogAct{act}); found && act.Act == "Recommend" {
+			return ""
+		}
+		values = append(values, act)
+	}
+	// values of other sentences are not in the question
+	template, _ := Delexicalize(question, values)
+	if i := strings.LastIndexAny(template, "，,"); i != -1 {
+		_, size := utf8.DecodeRuneInString(template[i:])
+		template = template[i+size:]
+	}
+	if strings.Contains(template, "$") {
+		return ""
+	}
+	return template
+}
+
+// lastSentence is the sentence of the utterance where the sys asks, "" if none
+func lastSentence(utterance string) string {
+	sentences := regSentence.FindAllString(normalizeSlotValue(utterance), -1)
+	if len(sentences) == 0 {
+		return ""
+	}
+	return sentences[len(sentences)-1]
+}
+
+// asksFor tells if the template mentions the slot name or a question word of it
+func asksFor(template string, slot string) bool {
+	if strings.Contains(template, slot) {
+		return true
+	}
+	reg, ok := regAskSlot[slot]
+	return ok && reg.MatchString(template)
+}
+
+// confirmedAct is the only value informed or recommended in a question sentence of a sys turn with its template,
+// nil if none. Turns of General+reqmore like 电话是$电话，还需要其他信息么？ are not confirmations.
+func confirmedAct(turn *crosswoz.Message, sentence string) (confirmed *crosswoz.DialogAct, template string) {
+	if !isQuestion(sentence) {
+		return nil, ""
+	}
+	for _, act := range turn.DialogActs {
+		if act.Act == "General" && act.Intent == "reqmore" {
+			return nil, ""
+		}
+		if act.Act != "Inform" && act.Act != "Recommend" || !hasValue(act) {
+			continue
+		}
+		delexicalized, found := Delexicalize(sentence, []*crosswoz.DialogAct{act})
+		if !found {
+			continue
+		}
+		if confirmed != nil {
+			return nil, ""
+		}
+		confirmed, template = act, delexicalized
+	}
+	return confirmed, template
+}
+
+func addPrompt(groups map[string]*PromptGroup, slot string, template string, utterance string) {
+	group, ok := groups[slot]
+	if !ok {
+		group = &PromptGroup{Slot: slot, Templates: make(map[string]*ResponseTemplate)}
+		groups[slot] = group
+	}
+	group.Turns++
+	addTemplate(group.Templates, template, utterance)
+}
+
+// Empty are the kinds of prompts of which no template is seen MinResponseCount times, like "ask slot"
+func (m *PromptMiner) Empty() (kinds []string) {
+	for _, kind := range []struct {
+		name   string
+		groups map[string]*PromptGroup
+	}{{"ask slot", m.AskSlot}, {"multi value", m.MultiValue}, {"confirm", m.Confirm}} {
+		empty := true
+		for _, group := range kind.groups {
+			if len(group.Prompts(1)) > 0 {
+				empty = false
+				break
+			}
+		}
+		if empty {
+			kinds = append(kinds, kind.name)
+		}
+	}
+	return kinds
+}
+
+// Attach replaces the prompts of the slots by their AttributeId with the mined ones,
+// slots without mined prompts keep theirs, and multi value prompts are for multi value slots only.
+// A slot allows confirm only with a confirm prompt seen MinResponseCount times.
+func (m *PromptMiner) Attach(intents []*p.IntentMeta, top int) {
+	if empty := m.Empty(); len(empty) > 0 {
+		log.Println("~~~~no mined prompts of", strings.Join(empty, ", "), "in", m.Turns, "sys turns, the slots keep theirs")
+	}
+	for _, intent := range intents {
+		for _, slot := range intent.Slots {
+			if prompts := m.AskSlot[slot.AttributeId].Prompts(top); len(prompts) > 0 {
+				slot.AskSlotPrompt = prompts
+			}
+			if prompts := m.MultiValue[slot.AttributeId].Prompts(top); len(prompts) > 0 && slot.AllowMultiValue {
+				slot.MultiValuePrompts = prompts
+			}
+			if prompts := m.Confirm[slot.AttributeId].Prompts(top); len(prompts) > 0 {
+				slot.AllowConfirm = true
+				slot.ConfirmPrompts = prompts
+			}
+		}
+	}
+}
+
+// PromptOverride gives the hand written prompts of a slot, which take precedence over mined ones
+type PromptOverride struct {
+	Slot       string   `json:"slot"` // the attribute id, like 酒店.价格
+	AskSlot    []string `json:"askSlot,omitempty"`
+	MultiValue []string `json:"multiValue,omitempty"`
+	Confirm    []string `json:"confirm,omitempty"` // slots with confirm prompts allow confirm
+}
+
+type PromptOverrides struct {
+	Prompts []*PromptOverride `json:"prompts"`
+}
+
+//go:embed prompt_overrides.json
+var defaultPromptOverrides []byte
+
+// SlotPromptOverrides are applied after mining, the default is prompt_overrides.json
+var SlotPromptOverrides = mustParsePromptOverrides(defaultPromptOverrides)
+
+func mustParsePromptOverrides(b []byte) *PromptOverrides {
+	overrides, err := ParsePromptOverrides(b)
+	if err != nil {
+		panic(err)
+	}
+	return overrides
+}
+
+// LoadPromptOverrides reads an override file in the format of prompt_overrides.json
+func LoadPromptOverrides(fileName string) (*PromptOverrides, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s, err: %w", fileName, err)
+	}
+	overrides, err := ParsePromptOverrides(b)
+	if err != nil {
+		return nil, fmt.Errorf("bad prompt overrides in %s, err: %w", fileName, err)
+	}
+	return overrides, nil
+}
+
+func ParsePromptOverrides(b []byte) (*PromptOverrides, error) {
+	overrides := &PromptOverrides{}
+	if err := json.Unmarshal(b, overrides); err != nil {
+		return nil, err
+	}
+	seen := make(map[string]bool)
+	for i, override := range overrides.Prompts {
+		if !strings.Contains(override.Slot, ".") {
+			return nil, fmt.Errorf("override %d: expect a slot like 酒店.价格, got %q", i, override.Slot)
+		}
+		if seen[override.Slot] {
+			return nil, fmt.Errorf("override %d: duplicated slot %s", i, override.Slot)
+		}
+		seen[override.Slot] = true
+		if len(override.AskSlot)+len(override.MultiValue)+len(override.Confirm) == 0 {
+			return nil, fmt.Errorf("override %d %s: no prompts", i, override.Slot)
+		}
+	}
+	return overrides, nil
+}
+
+// Apply replaces the prompts of the slots with the overrides, returns the override slots not found
+func (o *PromptOverrides) Apply(intents []*p.IntentMeta) (unknown []string) {
+	bySlot := make(map[string]*PromptOverride)
+	for _, override := range o.Prompts {
+		bySlot[override.Slot] = override
+	}
+	found := make(map[string]bool)
+	for _, intent := range intents {
+		for _, slot := range intent.Slots {
+			override, ok := bySlot[slot.AttributeId]
+			if !ok {
+				continue
+			}
+			found[slot.AttributeId] = true
+			if len(override.AskSlot) > 0 {
+				slot.AskSlotPrompt = override.AskSlot
+			}
+			if len(override.MultiValue) > 0 {
+				if !slot.AllowMultiValue {
+					log.Println("~~~~multi value prompts of a single value slot:", slot.AttributeId)
+				}
+				slot.MultiValuePrompts = override.MultiValue
+			}
+			if len(override.Confirm) > 0 {
+				slot.AllowConfirm = true
+				slot.ConfirmPrompts = override.Confirm
+			}
+		}
+	}
+	for _, override := range o.Prompts {
+		if !found[override.Slot] {
+			unknown = append(unknown, override.Slot)
+		}
+	}
+	return unknown
+}
@@ -0,0 +1,180 @@
+package generate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/framely/sgdnlu/generate_framely/framely/p"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz/crosswoztest"
+)
+
+func TestIsQuestion(t *testing.T) {
+	for _, c := range []struct {
+		sentence string
+		question bool
+	}{
+		{"推荐您去颐和园怎么样？", true},
+		{"您是要找餐馆还是景点呢?", true},
+		{"电话是010-83680606。", false},
+		{"需要其他信息么？", false},
+		{"您还有别的要求吗？", false},
+		{"还需要什么帮助吗？", false},
+	} {
+		if question := isQuestion(c.sentence); question != c.question {
+			t.Errorf("%s: expect %v, got %v", c.sentence, c.question, question)
+		}
+	}
+}
+
+func TestAskedSlots(t *testing.T) {
+	turn := crosswoztest.Turn
+	for _, c := range []struct {
+		sys, usr *crosswoz.Message
+		slots    []string
+		template string
+	}{
+		// a question holding recommended values is a choice
+		{turn("sys", "故宫和天坛你想去哪个？", [4]string{"Recommend", "景点", "名称", "故宫"}, [4]string{"Recommend", "景点", "名称", "天坛"}),
+			turn("usr", "天坛吧", [4]string{"Inform", "景点", "名称", "天坛"}), nil, ""},
+		// the informed values are delexicalized out of the question, the clause asking is the prompt
+		{turn("sys", "故宫附近的地铁站是天安门东站，请问要到哪里？", [4]string{"Inform", "地铁", "出发地", "故宫"},
+			[4]string{"Inform", "地铁", "出发地附近地铁站", "天安门东站"}),
+			turn("usr", "到天坛", [4]string{"Inform", "地铁", "目的地", "天坛"}),
+			[]string{"地铁.目的地"}, "请问要到哪里？"},
+		// only the slots of the question words are asked
+		{turn("sys", "您想吃人均多少钱的？"),
+			turn("usr", "100元左右，想吃烤鸭", [4]string{"Inform", "餐馆", "人均消费", "100元"}, [4]string{"Inform", "餐馆", "推荐菜", "烤鸭"}),
+			[]string{"餐馆.人均消费"}, "您想吃人均多少钱的？"},
+		// real questions of CrossWOZ asking for none of the slots informed next
+		{turn("sys", "不好意思，您要去的是什么地方？"),
+			turn("usr", "评分4.5分以上的餐馆", [4]string{"Inform", "餐馆", "评分", "4.5分以上"}), nil, ""},
+		{turn("sys", "您看可以吗？"),
+			turn("usr", "就去故宫", [4]string{"Inform", "景点", "名称", "故宫"}), nil, ""},
+		// a confirmation asks for no slot
+		{turn("sys", "故宫怎么样？", [4]string{"Recommend", "景点", "名称", "故宫"}),
+			turn("usr", "好的", [4]string{"Inform", "景点", "名称", "故宫"}), nil, ""},
+		{turn("sys", "还有什么需要吗？", [4]string{"General", "reqmore", "none", "none"}),
+			turn("usr", "故宫", [4]string{"Inform", "景点", "名称", "故宫"}), nil, ""},
+	} {
+		if slots, template := askedSlots(c.sys, c.usr, c.sys.Utterance); !reflect.DeepEqual(slots, c.slots) || template != c.template {
+			t.Errorf("%s: expect %v %s, got %v %s", c.sys.Utterance, c.slots, c.template, slots, template)
+		}
+	}
+}
+
+func TestMinePrompts(t *testing.T) {
+	reader, err := crosswoz.OpenDialogueReader("../../data/crosswoz/test.json.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	miner, err := MinePrompts(reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// real turns of CrossWOZ: 北京丽晶酒店怎么样？ of 5923 and 北京都季商旅酒店这个怎么样？ of 11023 confirm a name,
+	// 电话是010-83680606。需要其他信息么？ of 3639 is no confirmation
+	if prompts := miner.Confirm["酒店.名称"].Prompts(DefaultTopResponses); len(prompts) == 0 || prompts[0] != "$名称怎么样？" {
+		t.Errorf("unexpected confirm prompts %v", prompts)
+	}
+	if group := miner.Confirm["酒店.电话"]; group != nil {
+		t.Errorf("unexpected confirm prompts %+v", group.Templates)
+	}
+	// 请问您要到达的地点是哪里？ of 12187 is answered by informing the places again
+	if group := miner.MultiValue["地铁.目的地"]; group == nil || group.Templates["请问您要到达的地点是哪里？"] == nil {
+		t.Errorf("unexpected multi value prompts %+v", group)
+	}
+	// the sys seldom asks, choices like $名称和$名称都挺好的，你想去哪里呢？ and confirmations like 您看可以吗？
+	// are not ask slot prompts, and no ask slot or multi value prompt is seen twice with the val split
+	reader, err = crosswoz.OpenDialogueReader("../../data/crosswoz/val.json.zip")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	if err := crosswoz.ForEachDialogue(reader, nil, miner.AddDialogue); err != nil {
+		t.Fatal(err)
+	}
+	for _, groups := range []map[string]*PromptGroup{miner.AskSlot, miner.MultiValue} {
+		for slot, group := range groups {
+			for template := range group.Templates {
+				if !asksFor(template, slot[strings.Index(slot, ".")+1:]) {
+					t.Errorf("%s: unexpected prompt %s", slot, template)
+				}
+			}
+		}
+	}
+	if empty := miner.Empty(); !reflect.DeepEqual(empty, []string{"ask slot", "multi value"}) {
+		t.Errorf("unexpected empty prompts %v", empty)
+	}
+
+	intents := []*p.IntentMeta{{MetaId: "酒店", Slots: []*p.FramelySlot{
+		{AttributeId: "酒店.名称", AskSlotPrompt: []string{"酒店的名称是什么？"}},
+		{AttributeId: "酒店.电话", AskSlotPrompt: []string{"酒店的电话是什么？"}},
+		{AttributeId: "酒店.评分", AskSlotPrompt: []string{"这个酒店的评分是多少？"}},
+	}}}
+	miner.Attach(intents, DefaultTopResponses)
+	name, phone, score := intents[0].Slots[0], intents[0].Slots[1], intents[0].Slots[2]
+	// no ask slot prompt of a name is mined, the slot keeps its own
+	if !name.AllowConfirm || name.ConfirmPrompts[0] != "$名称怎么样？" || name.AskSlotPrompt[0] != "酒店的名称是什么？" {
+		t.Errorf("unexpected prompts of %+v", name)
+	}
+	// a confirm prompt seen once does not allow confirm
+	if phone.AllowConfirm || score.AllowConfirm || score.AskSlotPrompt[0] != "这个酒店的评分是多少？" {
+		t.Errorf("unexpected prompts of %+v %+v", phone, score)
+	}
+
+	// the ask slot and multi value prompts seen twice are attached, other slots keep theirs
+	asking := NewPromptMiner()
+	sys := crosswoztest.Turn("sys", "故宫附近的地铁站是天安门东站，请问要到哪里？", [4]string{"Inform", "地铁", "出发地", "故宫"},
+		[4]string{"Inform", "地铁", "出发地附近地铁站", "天安门东站"})
+	usr := crosswoztest.Turn("usr", "到天坛", [4]string{"Inform", "地铁", "目的地", "天坛"})
+	for _, informed := range []map[string]bool{{}, {}, {"地铁.目的地": true}, {"地铁.目的地": true}} {
+		asking.Add(sys, usr, informed)
+	}
+	subway := []*p.IntentMeta{{MetaId: "地铁", Slots: []*p.FramelySlot{
+		{AttributeId: "地铁.目的地", AllowMultiValue: true, AskSlotPrompt: []string{"目的地是什么？"}},
+		{AttributeId: "地铁.出发地", AskSlotPrompt: []string{"出发地是什么？"}},
+	}}}
+	asking.Attach(subway, DefaultTopResponses)
+	destination, origin := subway[0].Slots[0], subway[0].Slots[1]
+	if !reflect.DeepEqual(destination.AskSlotPrompt, []string{"请问要到哪里？"}) ||
+		!reflect.DeepEqual(destination.MultiValuePrompts, []string{"请问要到哪里？"}) ||
+		!reflect.DeepEqual(origin.AskSlotPrompt, []string{"出发地是什么？"}) || origin.MultiValuePrompts != nil {
+		t.Errorf("unexpected attached prompts %+v %+v", destination, origin)
+	}
+
+	// the overrides take precedence
+	overrides, err := ParsePromptOverrides([]byte(`{"prompts": [
+		{"slot": "酒店.名称", "confirm": ["就住$名称吗？"]},
+		{"slot": "酒店.评分", "askSlot": ["您对评分有什么要求？"]},
+		{"slot": "天气.温度", "askSlot": ["多少度？"]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if unknown := overrides.Apply(intents); !reflect.DeepEqual(unknown, []string{"天气.温度"}) {
+		t.Errorf("unexpected unknown slots %v", unknown)
+	}
+	if !reflect.DeepEqual(name.ConfirmPrompts, []string{"就住$名称吗？"}) || !reflect.DeepEqual(score.AskSlotPrompt, []string{"您对评分有什么要求？"}) ||
+		score.AllowConfirm || phone.AskSlotPrompt[0] != "酒店的电话是什么？" {
+		t.Errorf("unexpected overridden slots %+v %+v %+v", name, phone, score)
+	}
+}
+
+func TestParsePromptOverrides(t *testing.T) {
+	if len(SlotPromptOverrides.Prompts) == 0 {
+		t.Errorf("no default prompt overrides")
+	}
+	for _, b := range []string{
+		`{"prompts": [{"slot": "名称", "askSlot": ["名称是什么？"]}]}`,
+		`{"prompts": [{"slot": "餐馆.名称"}]}`,
+		`{"prompts": [{"slot": "餐馆.名称", "askSlot": ["a"]}, {"slot": "餐馆.名称", "confirm": ["b"]}]}`,
+		`{"prompts": `,
+	} {
+		if _, err := ParsePromptOverrides([]byte(b)); err == nil {
+			t.Errorf("expect an error of %s", b)
+		}
+	}
+}
@@ -1,12 +1,84 @@
 package generate
 
 import (
-	"fmt"
+	"reflect"
 	"testing"
 )
 
 func TestFindSpan(t *testing.T) {
-	fmt.Println(aliasesFor游玩时间("0.3小时-0.5小时"))
-	fmt.Println(aliasesFor游玩时间("0.5小时-1小时"))
-	fmt.Println(aliasesFor游玩时间("3小时-5小时"))
+	for _, c := range []struct {
+		slotName, slotValue string
+		aliases             []string
+	}{
+		{"游玩时间", "0.3小时-0.5小时", []string{"0.3-0.5小时", "0.3到0.5小时", "0.3小时~0.5小时", "0.3-0.5个小时", "0.3、0.5个小时", "0.3小时到0.5小时", "0.3到0.5个小时"}},
+		{"游玩时间", "1天-2天", []string{"1-2天"}},
+		{"游玩时间", "1小时", []string{"1个小时"}},
+		{"门票", "50-100元", []string{"20-100元", "50到100元", "50元到100元", "50到100元之间", "50元到100元之间", "50-100", "50-100之间", "50至100元", "50元至100元", "50—100元"}},
+		{"门票", "200元以上", []string{"200以上", "200元"}},
+		{"评分", "4.5分以上", []string{"4.5分", "4.5以上", "4.5是以上"}},
+		{"酒店类型", "经济型", []string{"经济"}},
+		{"名称", "北京饭店餐厅", []string{"北京饭店餐馆"}},
+		{"推荐菜", "烧羊肉", []string{"烤羊肉"}},
+		{"名称", "故宫", nil},
+	} {
+		if aliases := SpanAliasRules.Aliases(c.slotName, c.slotValue); !reflect.DeepEqual(aliases, c.aliases) {
+			t.Errorf("%s %s: expect %v, got %v", c.slotName, c.slotValue, c.aliases, aliases)
+		}
+	}
+
+	utterance := "我想找一个经济型酒店，价格在200到300元之间"
+	if fr, to := findSpan(utterance, "价格", "200-300元"); utterance[fr:to] != "200到300元" {
+		t.Errorf("unexpected span %d-%d", fr, to)
+	}
+	if fr, to := findSpan(utterance, "价格", "400-500元"); fr != -1 || to != -1 {
+		t.Errorf("expect no span, got %d-%d", fr, to)
+	}
+	// Chinese numerals of numeric slots
+	for _, c := range []struct{ utterance, slotName, slotValue, span string }{
+		{"能玩一个小时的景点", "游玩时间", "1小时", "一个小时"},
+		{"能玩两到三个小时", "游玩时间", "2小时-3小时", "两到三个小时"},
+		{"玩半小时就行", "游玩时间", "0.5小时", "半小时"},
+		{"要一个半小时", "游玩时间", "1.5小时", "一个半小时"},
+		{"评分四点五分以上", "评分", "4.5分以上", "四点五分以上"},
+		{"门票十五元", "门票", "15元", "十五元"},
+		{"能玩十二小时", "游玩时间", "2小时", ""},
+		{"一家两百元的酒店", "名称", "200元", ""},
+	} {
+		fr, to := findSpan(c.utterance, c.slotName, c.slotValue)
+		if (c.span == "" && fr != -1) || (c.span != "" && (fr == -1 || c.utterance[fr:to] != c.span)) {
+			t.Errorf("%s %s in %s: expect %s, got %d-%d", c.slotName, c.slotValue, c.utterance, c.span, fr, to)
+		}
+	}
+
+	// the alias is longer than the value
+	utterance = "有精品烤鸭三吗"
+	if fr, to := findSpan(utterance, "推荐菜", "精品烤鸭三吃"); utterance[fr:to] != "精品烤鸭三" {
+		t.Errorf("unexpected span %d-%d", fr, to)
+	}
+}
+
+func TestParseAliasRules(t *testing.T) {
+	for _, bad := range []string{
+		`{"rules": [{"name": "both", "value": "a", "pattern": "a", "aliases": ["b"]}]}`,
+		`{"rules": [{"name": "neither", "aliases": ["b"]}]}`,
+		`{"rules": [{"name": "no aliases", "value": "a"}]}`,
+		`{"rules": [{"name": "bad regex", "pattern": "(", "aliases": ["b"]}]}`,
+	} {
+		if _, err := ParseAliasRules([]byte(bad)); err == nil {
+			t.Errorf("expect an error for %s", bad)
+		}
+	}
+	rules, err := ParseAliasRules([]byte(`{"rules": [
+		{"name": "low", "priority": 1, "pattern": "^(\\d+)号$", "aliases": ["${1}"]},
+		{"name": "high", "priority": 2, "slots": ["地址"], "value": "1号", "aliases": ["一号"]}
+	]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if aliases := rules.Aliases("地址", "1号"); !reflect.DeepEqual(aliases, []string{"一号", "1"}) {
+		t.Errorf("unexpected aliases: %v", aliases)
+	}
+	if aliases := rules.Aliases("电话", "1号"); !reflect.DeepEqual(aliases, []string{"1"}) {
+		t.Errorf("unexpected aliases: %v", aliases)
+	}
 }
@@ -0,0 +1,204 @@
+package generate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+	"sort"
+	"strings"
+)
+
+// how a slot value is found in the utterance by ExtractSlotAnnotations
+const (
+	SpanExact   = "exact"   // the value itself
+	SpanAlias   = "alias"   // an alias of SpanAliasRules, or the value after normalizing Chinese numerals
+	SpanFuzzy   = "fuzzy"   // FuzzyFindSpan with a score of at least FuzzyThreshold
+	SpanMissing = "missing" // not annotated
+)
+
+var spanKinds = []string{SpanExact, SpanAlias, SpanFuzzy, SpanMissing}
+
+// at most this many example utterances of each kind for a slot
+const spanReportExamples = 3
+
+// SpanCount counts slot values by how they are found
+type SpanCount struct {
+	Total   int
+	Exact   int
+	Alias   int
+	Fuzzy   int
+	Missing int
+}
+
+func (c *SpanCount) add(kind string) {
+	c.Total++
+	switch kind {
+	case SpanExact:
+		c.Exact++
+	case SpanAlias:
+		c.Alias++
+	case SpanFuzzy:
+		c.Fuzzy++
+	case SpanMissing:
+		c.Missing++
+	}
+}
+
+// Coverage is the ratio of annotated values
+func (c *SpanCount) Coverage() float64 {
+	if c.Total == 0 {
+		return 0
+	}
+	return float64(c.Total-c.Missing) / float64(c.Total)
+}
+
+// SpanExample is an utterance with the slot value
+type SpanExample struct {
+	Utterance string
+	Value     string
+}
+
+// SlotSpanCoverage is the coverage of a slot, label is intent.slot
+type SlotSpanCoverage struct {
+	SpanCount
+	Values   map[string]*SpanCount     // by slot value
+	Examples map[string][]*SpanExample // by kind
+}
+
+// SpanReport is the slot span coverage of a split
+type SpanReport struct {
+	SpanCount
+	Slots map[string]*SlotSpanCoverage // by intent.slot
+}
+
+func NewSpanReport() *SpanReport {
+	return &SpanReport{Slots: make(map[string]*SlotSpanCoverage)}
+}
+
+// SpanCoverage collects how ExtractSlotAnnotations finds slot values, nil to not collect
+var SpanCoverage *SpanReport
+
+// Add counts a slot value of the label found in the utterance by kind
+func (r *SpanReport) Add(label string, value string, utterance string, kind string) {
+	slot, ok := r.Slots[label]
+	if !ok {
+		slot = &SlotSpanCoverage{
+			Values:   make(map[string]*SpanCount),
+			Examples: make(map[string][]*SpanExample),
+		}
+		r.Slots[label] = slot
+	}
+	r.add(kind)
+	slot.add(kind)
+	if _, ok := slot.Values[value]; !ok {
+		slot.Values[value] = &SpanCount{}
+	}
+	slot.Values[value].add(kind)
+	if len(slot.Examples[kind]) < spanReportExamples {
+		slot.Examples[kind] = append(slot.Examples[kind], &SpanExample{Utterance: utterance, Value: value})
+	}
+}
+
+func recordSpan(label string, value string, utterance string, kind string) {
+	if SpanCoverage != nil {
+		SpanCoverage.Add(label, value, utterance, kind)
+	}
+}
+
+// SortedLabels are the labels of slots in order
+func (r *SpanReport) SortedLabels() []string {
+	labels := make([]string, 0, len(r.Slots))
+	for label := range r.Slots {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// MissingValues are the values of the slot which are missing at least once, the most missing first
+func (s *SlotSpanCoverage) MissingValues() []string {
+	var values []string
+	for value, cnt := range s.Values {
+		if cnt.Missing > 0 {
+			values = append(values, value)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool {
+		mi, mj := s.Values[values[i]].Missing, s.Values[values[j]].Missing
+		if mi != mj {
+			return mi > mj
+		}
+		return values[i] < values[j]
+	})
+	return values
+}
+
+// Markdown renders the report as tables, the order is stable for diffing
+func (r *SpanReport) Markdown(title string) string {
+	var b strings.Builder
+	row := func(name string, c *SpanCount) {
+		fmt.Fprintf(&b, "| %s | %d | %d | %d | %d | %d | %.1f%% |\n",
+			name, c.Total, c.Exact, c.Alias, c.Fuzzy, c.Missing, 100*c.Coverage())
+	}
+	fmt.Fprintf(&b, "# %s\n\n", title)
+	b.WriteString("| slot | total | exact | alias | fuzzy | missing | coverage |\n")
+	b.WriteString("|---|---:|---:|---:|---:|---:|---:|\n")
+	labels := r.SortedLabels()
+	for _, label := range labels {
+		row(label, &r.Slots[label].SpanCount)
+	}
+	row("**all**", &r.SpanCount)
+
+	for _, label := range labels {
+		slot := r.Slots[label]
+		if slot.Alias+slot.Fuzzy+slot.Missing == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\n## %s\n", label)
+		if missing := slot.MissingValues(); len(missing) > 0 {
+			b.WriteString("\n| missing value | missing | total |\n|---|---:|---:|\n")
+			for _, value := range missing {
+				cnt := slot.Values[value]
+				fmt.Fprintf(&b, "| %s | %d | %d |\n", markdownEscape(value), cnt.Missing, cnt.Total)
+			}
+		}
+		for _, kind := range spanKinds {
+			if kind == SpanExact || len(slot.Examples[kind]) == 0 {
+				continue
+			}
+			fmt.Fprintf(&b, "\n%s:\n\n", kind)
+			for _, example := range slot.Examples[kind] {
+				fmt.Fprintf(&b, "- %s (%s)\n", markdownEscape(example.Utterance), markdownEscape(example.Value))
+			}
+		}
+	}
+	return b.String()
+}
+
+var markdownReplacer = strings.NewReplacer("|", "\\|", "\n", " ")
+
+func markdownEscape(s string) string {
+	return markdownReplacer.Replace(s)
+}
+
+// WriteSpanReport writes the report to outputDir/inputFile/span_report.json and span_report.md
+func WriteSpanReport(report *SpanReport, outputDir string, inputFile string) error {
+	os.MkdirAll(path.Join(outputDir, inputFile), 0755)
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	outputFile := path.Join(outputDir, inputFile, "span_report.json")
+	if err := ioutil.WriteFile(outputFile, b, 0666); err != nil {
+		return err
+	}
+	markdown := report.Markdown("Slot span coverage of " + inputFile)
+	if err := ioutil.WriteFile(path.Join(outputDir, inputFile, "span_report.md"), []byte(markdown), 0666); err != nil {
+		return err
+	}
+	log.Println("Wrote span report to", outputFile, "and span_report.md")
+	return nil
+}
@@ -0,0 +1,56 @@
+package generate
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSpanReport(t *testing.T) {
+	defer func(report *SpanReport, reviews []*SpanReview) {
+		SpanCoverage, SpanReviews = report, reviews
+	}(SpanCoverage, SpanReviews)
+	SpanCoverage = NewSpanReport()
+
+	for _, c := range []struct {
+		utterance string
+		slots     map[string]string
+	}{
+		{"我想去故宫", map[string]string{"名称": "故宫"}},
+		{"门票50到100元之间的", map[string]string{"门票": "50-100元"}},
+		{"能玩两个小时", map[string]string{"游玩时间": "2小时"}},
+		{"门票100-500元", map[string]string{"门票": "100-150元"}},
+		{"门票100-500元", map[string]string{"门票": "100-150元"}},
+	} {
+		ExtractSlotAnnotations(c.utterance, c.slots, "景点")
+	}
+	report := SpanCoverage
+	if report.SpanCount != (SpanCount{Total: 5, Exact: 1, Alias: 2, Missing: 2}) {
+		t.Errorf("unexpected counts: %+v", report.SpanCount)
+	}
+	if labels := report.SortedLabels(); !reflect.DeepEqual(labels, []string{"景点.名称", "景点.游玩时间", "景点.门票"}) {
+		t.Errorf("unexpected labels: %v", labels)
+	}
+	tickets := report.Slots["景点.门票"]
+	if tickets.SpanCount != (SpanCount{Total: 3, Alias: 1, Missing: 2}) || tickets.Values["100-150元"].Missing != 2 {
+		t.Errorf("unexpected counts of 门票: %+v", tickets.SpanCount)
+	}
+	if missing := tickets.MissingValues(); !reflect.DeepEqual(missing, []string{"100-150元"}) {
+		t.Errorf("unexpected missing values: %v", missing)
+	}
+
+	markdown := report.Markdown("test")
+	for _, line := range []string{
+		"| 景点.门票 | 3 | 0 | 1 | 0 | 2 | 33.3% |",
+		"| **all** | 5 | 1 | 2 | 0 | 2 | 60.0% |",
+		"| 100-150元 | 2 | 2 |",
+		"- 门票100-500元 (100-150元)",
+	} {
+		if !strings.Contains(markdown, line) {
+			t.Errorf("expect %s in\n%s", line, markdown)
+		}
+	}
+	if strings.Contains(markdown, "## 景点.名称") {
+		t.Errorf("expect no section of exact slots in\n%s", markdown)
+	}
+}
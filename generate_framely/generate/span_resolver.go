@@ -0,0 +1,106 @@
+package generate
+
+import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// SlotPriority breaks ties between spans of the same length of different slots, higher first, 0 by default.
+// Names and dishes are the most specific values of a domain.
+var SlotPriority = map[string]int{"名称": 2, "推荐菜": 1}
+
+// SpanCandidate is a span of a slot value in the utterance, Fr/To are UTF-8 byte offsets
+type SpanCandidate struct {
+	Slot  string // the slot name, or intent.slot of values of more than one intent
+	Value string
+	Fr    int
+	To    int
+	Kind  string // SpanExact or SpanAlias
+}
+
+func slotPriority(slot string) int {
+	return SlotPriority[slot[strings.Index(slot, ".")+1:]]
+}
+
+func (c *SpanCandidate) overlaps(other *SpanCandidate) bool {
+	return c.Fr < other.To && other.Fr < c.To
+}
+
+// SpanConflict is a span which is dropped for an overlapping span of another slot
+type SpanConflict struct {
+	Utterance string
+	Intent    string
+	Dropped   *SpanCandidate
+	Kept      *SpanCandidate
+}
+
+// SpanConflicts are collected by ExtractSlotAnnotations
+var SpanConflicts []*SpanConflict
+
+// resolveSpans picks non-overlapping spans of the candidates, the longer first,
+// then of the slot of higher SlotPriority, then exact values before aliases, then the earlier.
+// Every slot gets a span before more occurrences of a value are kept, so that 从故宫到故宫 has both 出发地 and 目的地.
+// Dropped spans of slots left without a span are returned as conflicts with the kept spans they overlap.
+// The kept spans are sorted by offsets.
+func resolveSpans(utterance string, intent string, candidates []*SpanCandidate) (kept []*SpanCandidate, conflicts []*SpanConflict) {
+	sorted := append([]*SpanCandidate(nil), candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if la, lb := utf8.RuneCountInString(utterance[a.Fr:a.To]), utf8.RuneCountInString(utterance[b.Fr:b.To]); la != lb {
+			return la > lb
+		}
+		if pa, pb := slotPriority(a.Slot), slotPriority(b.Slot); pa != pb {
+			return pa > pb
+		}
+		if a.Kind != b.Kind {
+			return a.Kind == SpanExact
+		}
+		if a.Fr != b.Fr {
+			return a.Fr < b.Fr
+		}
+		return a.Slot < b.Slot
+	})
+	blockerOf := func(candidate *SpanCandidate) *SpanCandidate {
+		for _, k := range kept {
+			if candidate.overlaps(k) {
+				return k
+			}
+		}
+		return nil
+	}
+	resolved := make(map[string]bool)
+	var dropped []*SpanConflict
+	for _, candidate := range sorted {
+		if resolved[candidate.Slot] {
+			continue
+		}
+		blocker := blockerOf(candidate)
+		if blocker == nil {
+			kept = append(kept, candidate)
+			resolved[candidate.Slot] = true
+			continue
+		}
+		dropped = append(dropped, &SpanConflict{
+			Utterance: utterance,
+			Intent:    intent,
+			Dropped:   candidate,
+			Kept:      blocker,
+		})
+	}
+	for _, conflict := range dropped {
+		if !resolved[conflict.Dropped.Slot] {
+			conflicts = append(conflicts, conflict)
+		}
+	}
+	// more occurrences of the values
+	for _, candidate := range sorted {
+		if blockerOf(candidate) == nil {
+			kept = append(kept, candidate)
+		}
+	}
+	sort.Slice(kept, func(i, j int) bool {
+		return kept[i].Fr < kept[j].Fr
+	})
+	return kept, conflicts
+}
@@ -0,0 +1,62 @@
+package generate
+
+import (
+	"testing"
+)
+
+func TestExtractSlotAnnotationsOverlap(t *testing.T) {
+	defer func(conflicts []*SpanConflict, reviews []*SpanReview) {
+		SpanConflicts, SpanReviews = conflicts, reviews
+	}(SpanConflicts, SpanReviews)
+
+	for _, c := range []struct {
+		utterance string
+		slots     map[string]string
+		spans     []string // text and slot of the annotations in order
+		conflicts int
+	}{
+		// the longer span wins, the shorter value is found elsewhere
+		{"北京饭店在北京", map[string]string{"名称": "北京饭店", "地址": "北京"},
+			[]string{"北京饭店", "景点.名称", "北京", "景点.地址"}, 0},
+		// the shorter value is only inside the longer one
+		{"我要去北京饭店", map[string]string{"名称": "北京饭店", "地址": "北京"},
+			[]string{"北京饭店", "景点.名称"}, 1},
+		// of the same length, 名称 has a higher priority
+		{"推荐一下烤鸭", map[string]string{"名称": "烤鸭", "推荐菜": "烤鸭"},
+			[]string{"烤鸭", "景点.名称"}, 1},
+		// every slot gets a span before more occurrences
+		{"从故宫到故宫", map[string]string{"出发地": "故宫", "目的地": "故宫"},
+			[]string{"故宫", "景点.出发地", "故宫", "景点.目的地"}, 0},
+		// the alias inside the value is not a conflict
+		{"门票200元以上的", map[string]string{"门票": "200元以上"},
+			[]string{"200元以上", "景点.门票"}, 0},
+	} {
+		for i := 0; i < 10; i++ {
+			SpanConflicts = nil
+			annotations := ExtractSlotAnnotations(c.utterance, c.slots, "景点")
+			var spans []string
+			for _, anno := range annotations {
+				spans = append(spans, c.utterance[anno.Fr:anno.To], anno.Label)
+			}
+			if len(spans) != len(c.spans) || len(SpanConflicts) != c.conflicts {
+				t.Fatalf("%s: expect %v and %d conflicts, got %v and %d conflicts", c.utterance, c.spans, c.conflicts, spans, len(SpanConflicts))
+			}
+			for j := range spans {
+				if spans[j] != c.spans[j] {
+					t.Fatalf("%s: expect %v, got %v", c.utterance, c.spans, spans)
+				}
+			}
+		}
+	}
+}
+
+func TestCandidateSpansEmptyValue(t *testing.T) {
+	for _, value := range []string{"", " ", "\t "} {
+		if spans := candidateSpans("门票多少钱", "门票", value); spans != nil {
+			t.Errorf("%q: expect no spans, got %v", value, spans)
+		}
+		if annotations := ExtractSlotAnnotations("门票多少钱", map[string]string{"门票": value}, "景点"); len(annotations) != 0 {
+			t.Errorf("%q: expect no annotations, got %v", value, annotations)
+		}
+	}
+}
@@ -0,0 +1,186 @@
+package nlg
+
+import (
+	"fmt"
+	"math"
+	"unicode"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+// MaxExamples is how many rendered turns are kept in a Report
+const MaxExamples = 20
+
+// bleuOrder is the highest n-gram of BLEU
+const bleuOrder = 4
+
+// tokens are the characters of the utterance without spaces, utterances are not segmented
+func tokens(utterance string) (chars []string) {
+	for _, r := range utterance {
+		if !unicode.IsSpace(r) {
+			chars = append(chars, string(r))
+		}
+	}
+	return chars
+}
+
+// ngramCounts counts the n-grams of the tokens
+func ngramCounts(chars []string, n int) map[string]int {
+	counts := make(map[string]int)
+	for i := 0; i+n <= len(chars); i++ {
+		key := ""
+		for _, c := range chars[i : i+n] {
+			key += c + " "
+		}
+		counts[key]++
+	}
+	return counts
+}
+
+// BLEU is the corpus BLEU-4 of the candidates against a reference each, on characters.
+// Like the smoothing method1 of nltk, which convlab2 evaluates with, an n-gram order without matches counts 0.1.
+func BLEU(candidates []string, references []string) (float64, error) {
+	if len(candidates) != len(references) {
+		return 0, fmt.Errorf("%d candidates against %d references", len(candidates), len(references))
+	}
+	matches := make([]int, bleuOrder)
+	totals := make([]int, bleuOrder)
+	candidateLen, referenceLen := 0, 0
+	for i := range candidates {
+		candidate, reference := tokens(candidates[i]), tokens(references[i])
+		candidateLen += len(candidate)
+		referenceLen += len(reference)
+		for n := 1; n <= bleuOrder; n++ {
+			referenceCounts := ngramCounts(reference, n)
+			for ngram, cnt := range ngramCounts(candidate, n) {
+				matches[n-1] += minInt(cnt, referenceCounts[ngram])
+				totals[n-1] += cnt
+			}
+		}
+	}
+	if candidateLen == 0 || matches[0] == 0 {
+		return 0, nil
+	}
+	logPrecision := 0.0
+	for n := 0; n < bleuOrder; n++ {
+		if totals[n] == 0 {
+			return 0, nil
+		}
+		match := float64(matches[n])
+		if matches[n] == 0 {
+			match = 0.1
+		}
+		logPrecision += math.Log(match/float64(totals[n])) / bleuOrder
+	}
+	brevity := 1.0
+	if candidateLen < referenceLen {
+		brevity = math.Exp(1 - float64(referenceLen)/float64(candidateLen))
+	}
+	return brevity * math.Exp(logPrecision), nil
+}
+
+// ExpectedSlots are the values of the acts to be filled, numbered like Rendering.Slots
+func ExpectedSlots(acts []*crosswoz.DialogAct) []string {
+	var slots []string
+	for _, act := range acts {
+		if act.Act != "Request" && act.Act != "General" && fillable(act) {
+			slots = append(slots, Signature(act))
+		}
+	}
+	return numberSlots(slots)
+}
+
+// SlotErrors counts the expected slots which are not filled and the filled ones which are not expected,
+// the slot error rate is (missing + redundant) / expected over the corpus
+func SlotErrors(expected []string, filled []string) (missing int, redundant int) {
+	filledSet := make(map[string]bool)
+	for _, slot := range filled {
+		filledSet[slot] = true
+	}
+	expectedSet := make(map[string]bool)
+	for _, slot := range expected {
+		expectedSet[slot] = true
+		if !filledSet[slot] {
+			missing++
+		}
+	}
+	for slot := range filledSet {
+		if !expectedSet[slot] {
+			redundant++
+		}
+	}
+	return missing, redundant
+}
+
+// RenderExample is a rendered sys turn
+type RenderExample struct {
+	Acts      string
+	Reference string
+	Utterance string
+}
+
+// Report is the evaluation of templates against the sys turns of dialogues
+type Report struct {
+	Turns            int
+	Rendered         int // turns with templates for all the acts
+	BLEU             float64
+	SlotErrorRate    float64
+	Slots            int // expected slots
+	Missing          int
+	Redundant        int
+	MissingTemplates map[string]int // combinations without templates, by turns
+	Examples         []*RenderExample
+}
+
+// Evaluate renders the acts of the sys turns of the dialogues, and evaluates the utterances against the turns
+func Evaluate(templates Templates, it crosswoz.DialogueIterator) (*Report, error) {
+	report := &Report{MissingTemplates: make(map[string]int)}
+	var candidates, references []string
+	err := crosswoz.ForEachDialogue(it, nil, func(dialogue *crosswoz.Dialogue) {
+		for _, turn := range dialogue.Turns {
+			if turn.Speaker != "sys" || len(turn.DialogActs) == 0 {
+				continue
+			}
+			report.Turns++
+			rendering, err := templates.Render(turn.DialogActs)
+			if err == nil {
+				report.Rendered++
+			}
+			for _, combination := range rendering.Missing {
+				report.MissingTemplates[combination]++
+			}
+			expected := ExpectedSlots(turn.DialogActs)
+			missing, redundant := SlotErrors(expected, rendering.Slots)
+			report.Slots += len(expected)
+			report.Missing += missing
+			report.Redundant += redundant
+
+			candidates = append(candidates, rendering.Utterance)
+			references = append(references, turn.Utterance)
+			if len(report.Examples) < MaxExamples {
+				report.Examples = append(report.Examples, &RenderExample{
+					Acts:      Combination(turn.DialogActs),
+					Reference: turn.Utterance,
+					Utterance: rendering.Utterance,
+				})
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if report.BLEU, err = BLEU(candidates, references); err != nil {
+		return nil, err
+	}
+	if report.Slots > 0 {
+		report.SlotErrorRate = float64(report.Missing+report.Redundant) / float64(report.Slots)
+	}
+	return report, nil
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,71 @@
+package nlg
+
+import (
+	"math"
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+func TestBLEU(t *testing.T) {
+	for _, c := range []struct {
+		candidates, references []string
+		bleu                   float64
+	}{
+		{[]string{"故宫的门票是60元。"}, []string{"故宫的门票是 60元。"}, 1},
+		{[]string{""}, []string{"不客气。"}, 0},
+		{[]string{"再见"}, []string{"不客气"}, 0},
+		// 5 of 6 unigrams, 3 of 5 bigrams, 1 of 4 trigrams, and no 4-grams of 3 smoothed
+		{[]string{"门票是60元"}, []string{"门票60元"}, math.Pow(5.0/6*3/5*1/4*0.1/3, 0.25)},
+		// shorter than the reference
+		{[]string{"门票60"}, []string{"门票是60"}, math.Exp(1-5.0/4) * math.Pow(4.0/4*2/3*0.1/2*0.1/1, 0.25)},
+	} {
+		bleu, err := BLEU(c.candidates, c.references)
+		if err != nil || math.Abs(bleu-c.bleu) > 1e-9 {
+			t.Errorf("%v against %v: expect %v, got %v, err: %v", c.candidates, c.references, c.bleu, bleu, err)
+		}
+	}
+	if _, err := BLEU([]string{"a"}, nil); err == nil {
+		t.Errorf("expect an error of unmatched references")
+	}
+}
+
+func TestSlotErrors(t *testing.T) {
+	expected := ExpectedSlots(acts([4]string{"Recommend", "景点", "名称", "故宫"}, [4]string{"Recommend", "景点", "名称", "天坛"},
+		[4]string{"Request", "景点", "门票", ""}, [4]string{"Inform", "酒店", "酒店设施-健身房", "是"}, [4]string{"General", "bye", "none", "none"}))
+	if len(expected) != 2 || expected[0] != "Recommend+景点+名称-1" || expected[1] != "Recommend+景点+名称-2" {
+		t.Fatalf("unexpected slots %v", expected)
+	}
+	if missing, redundant := SlotErrors(expected, []string{"Recommend+景点+名称-1", "Inform+景点+门票-1"}); missing != 1 || redundant != 1 {
+		t.Errorf("expect 1 missing and 1 redundant, got %d and %d", missing, redundant)
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	reader, err := crosswoz.OpenDialogueReader("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	templates, err := Mine(reader, DefaultTopTemplates, 1)
+	reader.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	reader, err = crosswoz.OpenDialogueReader("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	report, err := Evaluate(templates, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Turns == 0 || report.Rendered == 0 || report.Rendered > report.Turns || len(report.Examples) == 0 {
+		t.Errorf("unexpected report %+v", report)
+	}
+	// the templates are mined from the same turns
+	if report.BLEU < 0.5 || report.SlotErrorRate > 0.5 || report.Redundant != 0 {
+		t.Errorf("unexpected BLEU %v, slot error rate %v and %d redundant", report.BLEU, report.SlotErrorRate, report.Redundant)
+	}
+}
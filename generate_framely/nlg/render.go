@@ -0,0 +1,194 @@
+package nlg
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+// Rendering is an utterance rendered from dialog acts
+type Rendering struct {
+	Utterance string
+	Slots     []string // the filled values, like Inform+景点+门票-1, see SlotErrors
+	Missing   []string // combinations without a template
+}
+
+// Render renders the acts with the template of their combination. Without one, the acts are split by
+// Act+Intent in the order of the turn, then by signatures, and the sentences are joined.
+// The error lists the acts without templates, the rest of the acts are still rendered.
+// No acts render an empty utterance.
+func (t Templates) Render(acts []*crosswoz.DialogAct) (*Rendering, error) {
+	rendering := &Rendering{}
+	var sentences []string
+	for _, part := range t.split(acts) {
+		sentence, slots, ok := t.fill(part)
+		if !ok {
+			rendering.Missing = append(rendering.Missing, Combination(part))
+			continue
+		}
+		sentences = append(sentences, sentence)
+		rendering.Slots = append(rendering.Slots, slots...)
+	}
+	rendering.Utterance = joinSentences(sentences)
+	rendering.Slots = numberSlots(rendering.Slots)
+	if len(rendering.Missing) > 0 {
+		return rendering, fmt.Errorf("no template for %s", strings.Join(rendering.Missing, " and "))
+	}
+	return rendering, nil
+}
+
+// split splits the acts into parts which have templates, parts without templates are single signatures
+func (t Templates) split(acts []*crosswoz.DialogAct) [][]*crosswoz.DialogAct {
+	if _, ok := t[Combination(acts)]; ok {
+		return [][]*crosswoz.DialogAct{acts}
+	}
+	var parts [][]*crosswoz.DialogAct
+	for _, group := range groupActs(acts, func(act *crosswoz.DialogAct) string { return act.Act + "+" + act.Intent }) {
+		if _, ok := t[Combination(group)]; ok {
+			parts = append(parts, group)
+			continue
+		}
+		parts = append(parts, groupActs(group, Signature)...)
+	}
+	return parts
+}
+
+// groupActs groups the acts by key in the order of the first act of each group
+func groupActs(acts []*crosswoz.DialogAct, key func(act *crosswoz.DialogAct) string) (groups [][]*crosswoz.DialogAct) {
+	index := make(map[string]int)
+	for _, act := range acts {
+		k := key(act)
+		i, ok := index[k]
+		if !ok {
+			i = len(groups)
+			index[k] = i
+			groups = append(groups, nil)
+		}
+		groups[i] = append(groups[i], act)
+	}
+	return groups
+}
+
+// fill fills the values of the acts into the first template which has a placeholder for each slot
+// and no more placeholders than values, the extra values are joined into the last placeholder of their slot.
+// A template with as many placeholders as values is preferred.
+func (t Templates) fill(acts []*crosswoz.DialogAct) (sentence string, slots []string, ok bool) {
+	values := make(map[string][]*crosswoz.DialogAct)
+	for _, act := range acts {
+		if fillable(act) {
+			values[act.Slot] = append(values[act.Slot], act)
+		}
+	}
+	var fallback string
+	for _, template := range t[Combination(acts)] {
+		placeholders := countPlaceholders(template, values)
+		exact, usable := true, placeholders != nil
+		for slot, slotActs := range values {
+			if placeholders[slot] == 0 || placeholders[slot] > len(slotActs) {
+				usable = false
+			}
+			exact = exact && placeholders[slot] == len(slotActs)
+		}
+		if !usable {
+			continue
+		}
+		if exact {
+			sentence, slots = fillTemplate(template, values, placeholders)
+			return sentence, slots, true
+		}
+		if fallback == "" {
+			fallback = template
+		}
+	}
+	if fallback == "" {
+		return "", nil, false
+	}
+	sentence, slots = fillTemplate(fallback, values, countPlaceholders(fallback, values))
+	return sentence, slots, true
+}
+
+// nextPlaceholder finds the slot of the placeholder at the start of s, the longest slot name of the values
+func nextPlaceholder(s string, values map[string][]*crosswoz.DialogAct) string {
+	slot := ""
+	for name := range values {
+		if len(name) > len(slot) && strings.HasPrefix(s, "$"+name) {
+			slot = name
+		}
+	}
+	return slot
+}
+
+// countPlaceholders counts the placeholders of each slot in the template, nil if a placeholder has no values
+func countPlaceholders(template string, values map[string][]*crosswoz.DialogAct) map[string]int {
+	counts := make(map[string]int)
+	for i := strings.Index(template, "$"); i != -1; i = strings.Index(template, "$") {
+		slot := nextPlaceholder(template[i:], values)
+		if slot == "" {
+			return nil
+		}
+		counts[slot]++
+		template = template[i+len("$"+slot):]
+	}
+	return counts
+}
+
+// fillTemplate replaces the placeholders with values in the order of the acts
+func fillTemplate(template string, values map[string][]*crosswoz.DialogAct, placeholders map[string]int) (string, []string) {
+	var b strings.Builder
+	var slots []string
+	filled := make(map[string]int)
+	for i := strings.Index(template, "$"); i != -1; i = strings.Index(template, "$") {
+		b.WriteString(template[:i])
+		slot := nextPlaceholder(template[i:], values)
+		template = template[i+len("$"+slot):]
+
+		slotActs := values[slot][filled[slot]:]
+		filled[slot]++
+		if filled[slot] < placeholders[slot] {
+			slotActs = slotActs[:1]
+		}
+		for j, act := range slotActs {
+			if j > 0 {
+				b.WriteString("、")
+			}
+			b.WriteString(act.Value)
+			slots = append(slots, Signature(act))
+		}
+	}
+	b.WriteString(template)
+	return b.String(), slots
+}
+
+// numberSlots numbers repeated slots like the slot error rate of convlab2, Inform+景点+名称-1, Inform+景点+名称-2
+func numberSlots(slots []string) []string {
+	counter := make(map[string]int)
+	numbered := make([]string, len(slots))
+	for i, slot := range slots {
+		counter[slot]++
+		numbered[i] = fmt.Sprintf("%s-%d", slot, counter[slot])
+	}
+	return numbered
+}
+
+// joinSentences joins sentences with ，and ends them with 。unless they end with ! or ?
+func joinSentences(sentences []string) string {
+	var b strings.Builder
+	for i, sentence := range sentences {
+		sentence = strings.TrimRight(sentence, "。.，, ")
+		if sentence == "" {
+			continue
+		}
+		b.WriteString(sentence)
+		if strings.HasSuffix(sentence, "！") || strings.HasSuffix(sentence, "？") ||
+			strings.HasSuffix(sentence, "!") || strings.HasSuffix(sentence, "?") {
+			continue
+		}
+		if i == len(sentences)-1 {
+			b.WriteString("。")
+		} else {
+			b.WriteString("，")
+		}
+	}
+	return b.String()
+}
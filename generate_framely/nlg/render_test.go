@@ -0,0 +1,112 @@
+package nlg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz/crosswoztest"
+)
+
+var acts = crosswoztest.Acts
+
+func TestSignature(t *testing.T) {
+	for _, c := range []struct {
+		act       [4]string
+		signature string
+	}{
+		{[4]string{"Inform", "餐馆", "人均消费", "75元"}, "Inform+餐馆+人均消费"},
+		{[4]string{"Request", "酒店", "价格", ""}, "Request+酒店+价格"},
+		{[4]string{"General", "bye", "none", "none"}, "General+bye"},
+		{[4]string{"Inform", "酒店", "酒店设施-健身房", "是"}, "Inform+酒店+酒店设施-健身房+是"},
+		{[4]string{"Inform", "酒店", "酒店设施-健身房", "没有"}, "Inform+酒店+酒店设施-健身房+否"},
+		{[4]string{"Inform", "景点", "周边酒店", "无"}, "Inform+景点+周边酒店+无"},
+	} {
+		if signature := Signature(acts(c.act)[0]); signature != c.signature {
+			t.Errorf("%v: expect %s, got %s", c.act, c.signature, signature)
+		}
+	}
+}
+
+func TestRender(t *testing.T) {
+	templates := Templates{
+		"Inform+景点+名称,Inform+景点+门票":        {"$名称的门票是$门票。"},
+		"Recommend+景点+名称":                  {"为您推荐$名称、$名称、$名称。", "为您推荐$名称。"},
+		"Inform+景点+门票":                     {"门票是$门票。"},
+		"General+reqmore":                  {"还有什么需要吗？"},
+		"Inform+酒店+酒店设施-健身房+是":             {"有健身房。"},
+		"Inform+出租+车型,Inform+出租+车牌":        {"车型$车型，车牌$车牌。"},
+		"Inform+地铁+出发地,Inform+地铁+出发地附近地铁站": {"$出发地附近的地铁站是$出发地附近地铁站"},
+	}
+	for _, c := range []struct {
+		acts      []*crosswoz.DialogAct
+		utterance string
+		slots     []string
+		missing   []string
+	}{
+		{acts([4]string{"Inform", "景点", "门票", "60元"}, [4]string{"Inform", "景点", "名称", "故宫"}),
+			"故宫的门票是60元。", []string{"Inform+景点+名称-1", "Inform+景点+门票-1"}, nil},
+		// the template of as many placeholders as values first, extra values are joined
+		{acts([4]string{"Recommend", "景点", "名称", "故宫"}, [4]string{"Recommend", "景点", "名称", "天坛"}),
+			"为您推荐故宫、天坛。", []string{"Recommend+景点+名称-1", "Recommend+景点+名称-2"}, nil},
+		// split by Act+Intent without the template of the combination
+		{acts([4]string{"Inform", "景点", "门票", "60元"}, [4]string{"Inform", "酒店", "酒店设施-健身房", "是"},
+			[4]string{"General", "reqmore", "none", "none"}),
+			"门票是60元，有健身房，还有什么需要吗？", []string{"Inform+景点+门票-1"}, nil},
+		{acts([4]string{"Inform", "出租", "车型", "#CX"}, [4]string{"Inform", "出租", "车牌", "#CP"}, [4]string{"NoOffer", "景点", "none", "none"}),
+			"车型#CX，车牌#CP。", []string{"Inform+出租+车型-1", "Inform+出租+车牌-1"}, []string{"NoOffer+景点"}},
+		// the longest slot name of a placeholder
+		{acts([4]string{"Inform", "地铁", "出发地", "故宫"}, [4]string{"Inform", "地铁", "出发地附近地铁站", "天安门东"}),
+			"故宫附近的地铁站是天安门东。", []string{"Inform+地铁+出发地-1", "Inform+地铁+出发地附近地铁站-1"}, nil},
+		// nothing to render
+		{nil, "", []string{}, nil},
+	} {
+		rendering, err := templates.Render(c.acts)
+		if (err != nil) != (c.missing != nil) {
+			t.Errorf("%s: unexpected error %v", Combination(c.acts), err)
+		}
+		if rendering.Utterance != c.utterance || !reflect.DeepEqual(rendering.Slots, c.slots) || !reflect.DeepEqual(rendering.Missing, c.missing) {
+			t.Errorf("%s: expect %s %v %v, got %+v", Combination(c.acts), c.utterance, c.slots, c.missing, rendering)
+		}
+	}
+}
+
+func TestMine(t *testing.T) {
+	reader, err := crosswoz.OpenDialogueReader("../../data/crosswoz/demo2303.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer reader.Close()
+	templates, err := Mine(reader, DefaultTopTemplates, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("no templates are mined")
+	}
+	for combination, list := range templates {
+		if len(list) == 0 || len(list) > DefaultTopTemplates {
+			t.Errorf("%s: unexpected templates %v", combination, list)
+		}
+	}
+
+	// and loaded back
+	b, err := json.Marshal(templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fileName := path.Join(t.TempDir(), "nlg_templates.json")
+	if err := ioutil.WriteFile(fileName, b, 0666); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := LoadTemplates(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(loaded, templates) {
+		t.Errorf("expect %v, got %v", templates, loaded)
+	}
+}
@@ -0,0 +1,127 @@
+package nlg
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"sort"
+	"strings"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/generate"
+)
+
+// DefaultTopTemplates is how many templates of each combination are kept by Mine
+const DefaultTopTemplates = 5
+
+// Templates are delexicalized utterances by the combination of their dialog acts, the most frequent first, like
+// "Inform+景点+名称,Inform+景点+门票": ["$名称的门票是$门票。"]
+type Templates map[string][]string
+
+// fillable if the value of the act is filled into a template, values of boolean slots and 无 are not
+func fillable(act *crosswoz.DialogAct) bool {
+	if act.Value == "" || act.Value == "none" || act.Value == "无" {
+		return false
+	}
+	return !generate.IsBoolean(act.Intent, act.Slot)
+}
+
+// Signature is the template key of a dialog act, like Inform+餐馆+人均消费, Request+酒店+价格 and General+bye.
+// Values which are not filled in are kept, like Inform+酒店+酒店设施-健身房+是 and Inform+景点+周边酒店+无.
+func Signature(act *crosswoz.DialogAct) string {
+	key := act.Act + "+" + act.Intent
+	if act.Slot == "" || act.Slot == "none" {
+		return key
+	}
+	key += "+" + act.Slot
+	if act.Value == "" || act.Value == "none" || fillable(act) {
+		return key
+	}
+	value := act.Value
+	if yes, ok := generate.ParseBoolean(value); ok && generate.IsBoolean(act.Intent, act.Slot) {
+		value = "否"
+		if yes {
+			value = "是"
+		}
+	}
+	return key + "+" + value
+}
+
+// Combination is the sorted signatures of the acts without duplicates, the key of Templates
+func Combination(acts []*crosswoz.DialogAct) string {
+	signatures := make(map[string]bool)
+	for _, act := range acts {
+		signatures[Signature(act)] = true
+	}
+	return strings.Join(crosswoz.MapKeysSorted(signatures), ",")
+}
+
+// Mine delexicalizes the sys turns of the dialogues, keeps the top templates of each combination
+// which are seen at least minCount times, like generate.MinResponseCount
+func Mine(it crosswoz.DialogueIterator, top int, minCount int) (Templates, error) {
+	counts := make(map[string]map[string]int)
+	err := crosswoz.ForEachDialogue(it, nil, func(dialogue *crosswoz.Dialogue) {
+		for _, turn := range dialogue.Turns {
+			if turn.Speaker != "sys" || len(turn.DialogActs) == 0 {
+				continue
+			}
+			var acts []*crosswoz.DialogAct
+			for _, act := range turn.DialogActs {
+				if fillable(act) {
+					acts = append(acts, act)
+				}
+			}
+			template, complete := generate.Delexicalize(generate.NormalizeUtterance(turn.Utterance), acts)
+			if !complete {
+				continue
+			}
+			key := Combination(turn.DialogActs)
+			if counts[key] == nil {
+				counts[key] = make(map[string]int)
+			}
+			counts[key][template]++
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	templates := make(Templates)
+	for key, templateCounts := range counts {
+		var sorted []string
+		for template, cnt := range templateCounts {
+			if cnt >= minCount {
+				sorted = append(sorted, template)
+			}
+		}
+		// the more frequent first, then the shorter
+		sort.Slice(sorted, func(i, j int) bool {
+			a, b := sorted[i], sorted[j]
+			if templateCounts[a] != templateCounts[b] {
+				return templateCounts[a] > templateCounts[b]
+			}
+			if len(a) != len(b) {
+				return len(a) < len(b)
+			}
+			return a < b
+		})
+		if len(sorted) > top {
+			sorted = sorted[:top]
+		}
+		if len(sorted) > 0 {
+			templates[key] = sorted
+		}
+	}
+	return templates, nil
+}
+
+// LoadTemplates reads templates written as json, like the output of Mine
+func LoadTemplates(fileName string) (Templates, error) {
+	b, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var templates Templates
+	if err := json.Unmarshal(b, &templates); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
@@ -0,0 +1,313 @@
+package numeral
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// converts between Arabic numerals (15, 4.5) and Chinese numerals (十五, 四点五, 两, 半)
+
+var digitValues = map[rune]int{
+	'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4,
+	'五': 5, '六': 6, '七': 7, '八': 8, '九': 9,
+}
+
+var unitValues = map[rune]int{'十': 10, '百': 100, '千': 1000, '万': 10000}
+
+var chineseDigits = []string{"零", "一", "二", "三", "四", "五", "六", "七", "八", "九"}
+
+var chineseUnits = []string{"", "十", "百", "千"}
+
+// IsNumeral if r is a Chinese digit or unit, 点 and 半 are not
+func IsNumeral(r rune) bool {
+	_, isDigit := digitValues[r]
+	_, isUnit := unitValues[r]
+	return isDigit || isUnit
+}
+
+// ParseChinese parses Chinese numerals like 两, 十五, 一百零五, 三千二, 四点五, 二零一九 and 半
+func ParseChinese(s string) (float64, error) {
+	if s == "半" {
+		return 0.5, nil
+	}
+	integer, decimal := s, ""
+	if i := strings.Index(s, "点"); i != -1 {
+		integer, decimal = s[:i], s[i+len("点"):]
+		if decimal == "" {
+			return 0, fmt.Errorf("no decimal after 点 in %s", s)
+		}
+	}
+	n, err := parseChineseInt(integer)
+	if err != nil {
+		return 0, err
+	}
+	v := float64(n)
+	scale := 0.1
+	for _, r := range decimal {
+		d, ok := digitValues[r]
+		if !ok {
+			if r < '0' || r > '9' {
+				return 0, fmt.Errorf("bad decimal %s in %s", decimal, s)
+			}
+			d = int(r - '0')
+		}
+		v += float64(d) * scale
+		scale /= 10
+	}
+	// 四点五 should be 4.5, not 4.500000000000001
+	return strconv.ParseFloat(strconv.FormatFloat(v, 'f', utf8.RuneCountInString(decimal), 64), 64)
+}
+
+func parseChineseInt(s string) (int, error) {
+	if s == "" {
+		return 0, fmt.Errorf("empty numeral")
+	}
+	runes := []rune(s)
+	hasUnit := false
+	for _, r := range runes {
+		if !IsNumeral(r) {
+			return 0, fmt.Errorf("not a numeral: %s", s)
+		}
+		if _, ok := unitValues[r]; ok {
+			hasUnit = true
+		}
+	}
+	if !hasUnit {
+		// read digit by digit, like 二零一九
+		n := 0
+		for _, r := range runes {
+			n = n*10 + digitValues[r]
+		}
+		return n, nil
+	}
+	total, section, number := 0, 0, -1
+	lastUnit := 0      // the last unit of the section, units should go down like 千 百 十
+	afterZero := false // 一百零五, the digit after 零 has no unit
+	for i, r := range runes {
+		if d, ok := digitValues[r]; ok {
+			if number > 0 {
+				return 0, fmt.Errorf("two digits without unit in %s", s)
+			}
+			if d == 0 {
+				afterZero = true
+			}
+			number = d
+			continue
+		}
+		unit := unitValues[r]
+		if unit == 10000 {
+			if number > 0 {
+				section += number
+			}
+			if section == 0 {
+				return 0, fmt.Errorf("万 without number in %s", s)
+			}
+			total += section * 10000
+			section, number, lastUnit, afterZero = 0, -1, 0, false
+			continue
+		}
+		if lastUnit != 0 && unit >= lastUnit {
+			return 0, fmt.Errorf("bad order of units in %s", s)
+		}
+		if number <= 0 {
+			if unit != 10 || i != 0 {
+				return 0, fmt.Errorf("%c without number in %s", r, s)
+			}
+			// 十五 is 一十五
+			number = 1
+		}
+		section += number * unit
+		number, lastUnit, afterZero = -1, unit, false
+	}
+	if number > 0 {
+		if lastUnit > 10 && !afterZero {
+			// 三千二 is 3200, 一百五 is 150
+			number *= lastUnit / 10
+		} else if lastUnit == 0 && total > 0 && section == 0 && !afterZero {
+			// 一万二 is 12000
+			number *= 1000
+		}
+		section += number
+	}
+	return total + section, nil
+}
+
+// FormatNumber formats an Arabic number without trailing zeros, like 4.5, 15
+func FormatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// FormatChineseInt formats a non-negative integer less than 100000000, like 15 -> 十五, 105 -> 一百零五
+func FormatChineseInt(n int) string {
+	if n < 0 || n >= 100000000 {
+		return strconv.Itoa(n)
+	}
+	if n == 0 {
+		return chineseDigits[0]
+	}
+	if n >= 10000 {
+		high, low := n/10000, n%10000
+		s := formatSection(high) + "万"
+		if low == 0 {
+			return s
+		}
+		if low < 1000 {
+			s += chineseDigits[0]
+		}
+		return s + formatSectionInner(low)
+	}
+	return formatSection(n)
+}
+
+// formatSection formats 1-9999, the leading 一十 is 十
+func formatSection(n int) string {
+	s := formatSectionInner(n)
+	if strings.HasPrefix(s, "一十") {
+		return strings.TrimPrefix(s, "一")
+	}
+	return s
+}
+
+func formatSectionInner(n int) string {
+	var b strings.Builder
+	zero := false
+	for i := 3; i >= 0; i-- {
+		pow := 1
+		for j := 0; j < i; j++ {
+			pow *= 10
+		}
+		d := n / pow % 10
+		if d == 0 {
+			if b.Len() > 0 {
+				zero = true
+			}
+			continue
+		}
+		if zero {
+			b.WriteString(chineseDigits[0])
+			zero = false
+		}
+		b.WriteString(chineseDigits[d])
+		b.WriteString(chineseUnits[i])
+	}
+	return b.String()
+}
+
+// FormatChinese formats an Arabic number like "4.5" -> 四点五, "15" -> 十五, "0.5" -> 零点五
+func FormatChinese(number string) (string, error) {
+	integer, decimal := number, ""
+	if i := strings.Index(number, "."); i != -1 {
+		integer, decimal = number[:i], number[i+1:]
+	}
+	n, err := strconv.Atoi(integer)
+	if err != nil || n < 0 {
+		return "", fmt.Errorf("not a number: %s", number)
+	}
+	s := FormatChineseInt(n)
+	if decimal == "" {
+		return s, nil
+	}
+	s += "点"
+	for _, r := range decimal {
+		if r < '0' || r > '9' {
+			return "", fmt.Errorf("not a number: %s", number)
+		}
+		s += chineseDigits[r-'0']
+	}
+	return s, nil
+}
+
+// units after which 半 means 0.5, like 半小时, 半天
+var halfUnits = []string{"小时", "个小时", "天"}
+
+// Normalize rewrites the Chinese numerals in the text into Arabic ones,
+// like 两个小时 -> 2个小时, 三到五小时 -> 3到5小时, 四点五分 -> 4.5分, 半小时 -> 0.5小时, 一个半小时 -> 1.5个小时.
+// offsets maps every byte offset of the normalized text (and its end) to the byte offset in the text,
+// it is -1 inside a rewritten numeral, so that a span never starts or ends in the middle of a numeral.
+func Normalize(text string) (normalized string, offsets []int) {
+	var b strings.Builder
+	// a rewritten numeral starting at start of the text
+	write := func(s string, start int) {
+		offsets = append(offsets, start)
+		for i := 1; i < len(s); i++ {
+			offsets = append(offsets, -1)
+		}
+		b.WriteString(s)
+	}
+	i := 0
+	for i < len(text) {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if end, value, ok := readNumeral(text, i); ok {
+			write(value, i)
+			i = end
+			continue
+		}
+		if r == '半' && hasAnyPrefix(text[i+size:], halfUnits) {
+			write("0.5", i)
+			i += size
+			continue
+		}
+		for j := 0; j < size; j++ {
+			offsets = append(offsets, i+j)
+		}
+		b.WriteString(text[i : i+size])
+		i += size
+	}
+	offsets = append(offsets, len(text))
+	return b.String(), offsets
+}
+
+// readNumeral reads a numeral at i, Chinese with an optional decimal, or Arabic followed by 个半
+func readNumeral(text string, i int) (end int, value string, ok bool) {
+	end = i
+	for end < len(text) {
+		r, size := utf8.DecodeRuneInString(text[end:])
+		if !IsNumeral(r) {
+			break
+		}
+		end += size
+	}
+	chinese := end > i
+	if !chinese {
+		for end < len(text) && text[end] >= '0' && text[end] <= '9' {
+			end++
+		}
+		if end == i || !strings.HasPrefix(text[end:], "个半") {
+			return i, "", false
+		}
+		return end + len("个半"), text[i:end] + ".5个", true
+	}
+	if strings.HasPrefix(text[end:], "点") {
+		decimalEnd := end + len("点")
+		for decimalEnd < len(text) {
+			r, size := utf8.DecodeRuneInString(text[decimalEnd:])
+			if _, ok := digitValues[r]; !ok {
+				break
+			}
+			decimalEnd += size
+		}
+		if decimalEnd > end+len("点") {
+			end = decimalEnd
+		}
+	}
+	v, err := ParseChinese(text[i:end])
+	if err != nil {
+		return i, "", false
+	}
+	value = FormatNumber(v)
+	if strings.HasPrefix(text[end:], "个半") {
+		return end + len("个半"), value + ".5个", true
+	}
+	return end, value, true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,69 @@
+package numeral
+
+import (
+	"testing"
+)
+
+func TestParseChinese(t *testing.T) {
+	for s, expected := range map[string]float64{
+		"一": 1, "两": 2, "十": 10, "十五": 15, "二十": 20, "二十五": 25, "一百": 100, "一百零五": 105,
+		"一百五": 150, "两百": 200, "三千二": 3200, "一千零二十": 1020, "一万二千": 12000, "一万二": 12000, "一万零二": 10002, "十万": 100000,
+		"二零一九": 2019, "四点五": 4.5, "零点五": 0.5, "三点一四": 3.14, "半": 0.5,
+	} {
+		if v, err := ParseChinese(s); err != nil || v != expected {
+			t.Errorf("%s: expect %v, got %v, err: %v", s, expected, v, err)
+		}
+	}
+	for _, s := range []string{"", "百", "五十十", "十百", "三五十", "四点", "一个"} {
+		if v, err := ParseChinese(s); err == nil {
+			t.Errorf("expect an error for %s, got %v", s, v)
+		}
+	}
+}
+
+func TestFormatChinese(t *testing.T) {
+	for number, expected := range map[string]string{
+		"0": "零", "2": "二", "10": "十", "15": "十五", "105": "一百零五", "110": "一百一十", "1020": "一千零二十",
+		"12000": "一万二千", "10005": "一万零五", "4.5": "四点五", "0.5": "零点五",
+	} {
+		s, err := FormatChinese(number)
+		if err != nil || s != expected {
+			t.Errorf("%s: expect %s, got %s, err: %v", number, expected, s, err)
+		}
+		// and back
+		if v, err := ParseChinese(s); err != nil || FormatNumber(v) != number {
+			t.Errorf("%s: round trip got %v, err: %v", s, v, err)
+		}
+	}
+	if _, err := FormatChinese("4.5分"); err == nil {
+		t.Error("expect an error for 4.5分")
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	for text, expected := range map[string]string{
+		"两个小时":     "2个小时",
+		"三到五小时":    "3到5小时",
+		"四点五分以上":   "4.5分以上",
+		"半小时":      "0.5小时",
+		"一个半小时":    "1.5个小时",
+		"2个半小时":    "2.5个小时",
+		"十五元":      "15元",
+		"这一半不算":    "这1半不算",
+		"景点有什么":    "景点有什么",
+		"人均100元左右": "人均100元左右",
+	} {
+		normalized, offsets := Normalize(text)
+		if normalized != expected {
+			t.Errorf("%s: expect %s, got %s", text, expected, normalized)
+		}
+		if len(offsets) != len(normalized)+1 || offsets[0] != 0 || offsets[len(normalized)] != len(text) {
+			t.Errorf("%s: bad offsets %v", text, offsets)
+		}
+	}
+	normalized, offsets := Normalize("玩十二小时")
+	i := len("玩")
+	if normalized[i:i+2] != "12" || offsets[i] != i || offsets[i+1] != -1 || offsets[i+2] != i+len("十二") {
+		t.Errorf("unexpected offsets of %s: %v", normalized, offsets)
+	}
+}
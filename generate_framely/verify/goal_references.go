@@ -0,0 +1,103 @@
+package verify
+
+import (
+	"fmt"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/database"
+)
+
+// ReferenceIssue is a 周边 reference of the goal which the final_goal does not satisfy
+type ReferenceIssue struct {
+	DialogueID string
+	Ref        *crosswoz.GoalReference
+	From       string // 名称 of the referencing sub-goal in final_goal
+	To         string // 名称 of the referenced sub-goal in final_goal
+	Msg        string // also why the goal or final_goal of the dialogue can not be parsed, without Ref
+}
+
+// ReferenceReport of the 周边 references of all the goals
+type ReferenceReport struct {
+	Dialogues  int
+	References int
+	Issues     []*ReferenceIssue
+}
+
+// ResolveNearbyReference lists the candidates of a reference like "出现在id=3的周边餐馆里",
+// given the 名称 of the referenced sub-goal
+func ResolveNearbyReference(graph *database.NearbyGraph, goal *crosswoz.Goal, ref *crosswoz.GoalReference, toName string) ([]database.Entity, error) {
+	if ref.Kind != crosswoz.RefNearby {
+		return nil, fmt.Errorf("not a nearby reference: %+v", ref)
+	}
+	subGoals := goal.SubGoalsByID(ref.To)
+	if len(subGoals) == 0 {
+		return nil, fmt.Errorf("unknown sub-goal %d", ref.To)
+	}
+	candidates := graph.Neighbors(subGoals[0].Domain, toName, ref.NearbyDomain)
+	if len(candidates) == 0 {
+		return nil, fmt.Errorf("no 周边%s of %s %s", ref.NearbyDomain, subGoals[0].Domain, toName)
+	}
+	return candidates, nil
+}
+
+// VerifyNearbyReferences resolves the 周边 references of the goals against the nearby graph,
+// and checks that the final_goal picked one of the candidates.
+// A dialogue whose goal or final_goal can not be parsed is an issue, the other dialogues are still verified.
+func VerifyNearbyReferences(graph *database.NearbyGraph, it crosswoz.DialogueIterator) (*ReferenceReport, error) {
+	report := &ReferenceReport{}
+	err := crosswoz.ForEachDialogue(it, nil, func(dialogue *crosswoz.Dialogue) {
+		report.Dialogues++
+		goal, err := dialogue.ParsedGoal()
+		if err != nil {
+			report.Issues = append(report.Issues, &ReferenceIssue{DialogueID: dialogue.DialogueID, Msg: "bad goal: " + err.Error()})
+			return
+		}
+		finalGoal, err := dialogue.ParsedFinalGoal()
+		if err != nil {
+			report.Issues = append(report.Issues, &ReferenceIssue{DialogueID: dialogue.DialogueID, Msg: "bad final_goal: " + err.Error()})
+			return
+		}
+		for _, ref := range goal.References {
+			if ref.Kind != crosswoz.RefNearby {
+				continue
+			}
+			report.References++
+			issue := &ReferenceIssue{
+				DialogueID: dialogue.DialogueID,
+				Ref:        ref,
+				From:       finalGoalValue(finalGoal, ref.From, ref.Slot),
+				To:         finalGoalValue(finalGoal, ref.To, "名称"),
+			}
+			candidates, resolveErr := ResolveNearbyReference(graph, goal, ref, issue.To)
+			switch {
+			case resolveErr != nil:
+				issue.Msg = resolveErr.Error()
+			case !containsEntity(candidates, issue.From):
+				issue.Msg = fmt.Sprintf("%s is not one of the %d candidates", issue.From, len(candidates))
+			default:
+				continue
+			}
+			report.Issues = append(report.Issues, issue)
+		}
+	})
+	return report, err
+}
+
+// finalGoalValue is the single value of the slot in final_goal, empty if missing
+func finalGoalValue(finalGoal *crosswoz.Goal, id int, slotName string) string {
+	for _, subGoal := range finalGoal.SubGoalsByID(id) {
+		if slot := subGoal.Slot(slotName); slot != nil && slot.Values.Single != nil {
+			return *slot.Values.Single
+		}
+	}
+	return ""
+}
+
+func containsEntity(entities []database.Entity, name string) bool {
+	for _, entity := range entities {
+		if entity.EntityName() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package verify
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/database"
+)
+
+func TestVerifyNearbyReferences(t *testing.T) {
+	db, dialogues := loadTestData(t)
+	graph := database.NewNearbyGraph(db)
+	report, err := VerifyNearbyReferences(graph, crosswoz.NewSliceIterator(dialogues))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 餐馆 5 is 出现在id=3的周边餐馆里, but 大渔铁板烧(蓝色港湾店) is not near 北京丽晶酒店 in the database
+	if report.References != 1 || len(report.Issues) != 1 || report.Issues[0].From != "大渔铁板烧(蓝色港湾店)" || report.Issues[0].To != "北京丽晶酒店" {
+		t.Errorf("expect the reference to be reported: %+v", report)
+	}
+
+	// a bad final_goal is an issue of its dialogue, the following dialogues are still verified
+	bad := &crosswoz.Dialogue{DialogueID: "bad", FinalGoal: [][]interface{}{{"x", "景点", "名称", "", false}}}
+	report, err = VerifyNearbyReferences(graph, crosswoz.NewSliceIterator(append([]*crosswoz.Dialogue{bad}, dialogues...)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Dialogues != len(dialogues)+1 || report.References != 1 || len(report.Issues) != 2 ||
+		report.Issues[0].DialogueID != "bad" || report.Issues[0].Ref != nil || !strings.HasPrefix(report.Issues[0].Msg, "bad final_goal") {
+		t.Errorf("expect the bad final_goal and the reference to be reported: %+v", report)
+	}
+
+	goal, _ := dialogues[0].ParsedGoal()
+	candidates, err := ResolveNearbyReference(graph, goal, goal.References[len(goal.References)-1], "北京丽晶酒店")
+	if err != nil || !containsEntity(candidates, "老书虫") || containsEntity(candidates, "大渔铁板烧(蓝色港湾店)") {
+		t.Errorf("unexpected candidates: %v, err: %v", candidates, err)
+	}
+	if _, err := ResolveNearbyReference(graph, goal, goal.References[0], "北京丽晶酒店"); err == nil {
+		t.Error("expect an error for a name reference")
+	}
+}
@@ -0,0 +1,279 @@
+package verify
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/database"
+)
+
+// informed value of a missing attribute
+const noValue = "无"
+
+var numericSlots = map[string]bool{"门票": true, "人均消费": true, "价格": true, "评分": true}
+
+// InformMismatch is an informed value which is not the value in the database
+type InformMismatch struct {
+	DialogueID string
+	TurnIdx    int
+	Utterance  string
+	Domain     string
+	Slot       string
+	Informed   string
+	Entities   []string // the entities being discussed
+	Expected   []string // their values in the database
+}
+
+// SlotCount of checked and mismatched informed values
+type SlotCount struct {
+	Checked    int
+	Mismatched int
+}
+
+// InformReport of all the sys Inform acts
+type InformReport struct {
+	Dialogues int
+	// domain -> slot -> count
+	Slots map[string]map[string]*SlotCount
+	// informed attributes whose entity can not be resolved, domain -> count
+	Unresolved map[string]int
+	Mismatches []*InformMismatch
+}
+
+func (report *InformReport) count(domain string, slot string, mismatched bool) {
+	if _, ok := report.Slots[domain]; !ok {
+		report.Slots[domain] = make(map[string]*SlotCount)
+	}
+	cnt, ok := report.Slots[domain][slot]
+	if !ok {
+		cnt = &SlotCount{}
+		report.Slots[domain][slot] = cnt
+	}
+	cnt.Checked++
+	if mismatched {
+		cnt.Mismatched++
+	}
+}
+
+// VerifyInformValues compares the value of every sys Inform act with the database record of the entity
+// being discussed. The entity is the informed 名称 of the turn, otherwise the selectedResults of the domain
+// in sys_state, otherwise the last informed 名称 of the domain in the dialogue.
+// With several candidates, the value only needs to agree with one of them.
+func VerifyInformValues(db *database.DB, it crosswoz.DialogueIterator) (*InformReport, error) {
+	report := &InformReport{
+		Slots:      make(map[string]map[string]*SlotCount),
+		Unresolved: make(map[string]int),
+	}
+	err := crosswoz.ForEachDialogue(it, nil, func(dialogue *crosswoz.Dialogue) {
+		report.Dialogues++
+		lastNames := make(map[string][]string)
+		for i, turn := range dialogue.Turns {
+			if turn.Speaker != "sys" {
+				continue
+			}
+			informedNames := make(map[string][]string)
+			for _, act := range turn.DialogActs {
+				if act.Act == "Inform" && act.Slot == "名称" {
+					informedNames[act.Intent] = append(informedNames[act.Intent], act.Value)
+				}
+			}
+			for _, act := range turn.DialogActs {
+				if act.Act != "Inform" || act.Slot == "名称" || act.Intent == database.DomainTaxi {
+					continue
+				}
+				var expected []string
+				var names []string
+				if act.Intent == database.DomainMetro {
+					names, expected = expectedMetroStation(db, turn.SysState, act.Slot)
+				} else {
+					names = informedNames[act.Intent]
+					if len(names) == 0 {
+						names = turn.SysState.SelectedResults(act.Intent)
+					}
+					if len(names) == 0 {
+						names = lastNames[act.Intent]
+					}
+					expected = expectedValues(db, act.Intent, names, act.Slot)
+				}
+				if len(expected) == 0 {
+					report.Unresolved[act.Intent]++
+					continue
+				}
+				mismatched := !agrees(act.Slot, act.Value, expected)
+				report.count(act.Intent, act.Slot, mismatched)
+				if mismatched {
+					report.Mismatches = append(report.Mismatches, &InformMismatch{
+						DialogueID: dialogue.DialogueID,
+						TurnIdx:    i,
+						Utterance:  turn.Utterance,
+						Domain:     act.Intent,
+						Slot:       act.Slot,
+						Informed:   act.Value,
+						Entities:   names,
+						Expected:   expected,
+					})
+				}
+			}
+			for domain, names := range informedNames {
+				lastNames[domain] = names
+			}
+		}
+	})
+	return report, err
+}
+
+// expectedValues are the values of the slot for each entity found in the database
+func expectedValues(db *database.DB, domain string, names []string, slot string) (values []string) {
+	for _, name := range names {
+		entity, ok := db.Get(domain, name)
+		if !ok {
+			continue
+		}
+		values = append(values, attributeValue(entity, slot))
+	}
+	return values
+}
+
+// expectedMetroStation of 出发地附近地铁站 or 目的地附近地铁站, the places are in sys_state
+func expectedMetroStation(db *database.DB, state crosswoz.SystemState, slot string) (names []string, values []string) {
+	place := state.Constraint(database.DomainMetro, strings.TrimSuffix(slot, "附近地铁站"))
+	if place == "" {
+		return nil, nil
+	}
+	station, ok := db.MetroStationOf(place)
+	if !ok {
+		return []string{place}, nil
+	}
+	if station == "" {
+		station = noValue
+	}
+	return []string{place}, []string{station}
+}
+
+// attributeValue is the value as it should be informed, "无" if missing, 是/否 for 酒店设施-XX,
+// lists are joined by spaces
+func attributeValue(entity database.Entity, slot string) string {
+	if hotel, ok := entity.(*database.Hotel); ok && strings.HasPrefix(slot, "酒店设施-") {
+		if hotel.HasFacility(strings.TrimPrefix(slot, "酒店设施-")) {
+			return "是"
+		}
+		return "否"
+	}
+	switch v := entity.Attributes()[slot].(type) {
+	case string:
+		return v
+	case int:
+		return strconv.Itoa(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case []string:
+		if len(v) == 0 {
+			return noValue
+		}
+		return strings.Join(v, " ")
+	}
+	return noValue
+}
+
+// agrees if the informed value matches any of the expected values
+func agrees(slot string, informed string, expected []string) bool {
+	raw := informed
+	informed = NormalizeValue(slot, informed)
+	for _, value := range expected {
+		if strings.HasPrefix(slot, "周边") || slot == "推荐菜" {
+			// one of the list is informed at a time
+			if informed == noValue && value == noValue {
+				return true
+			}
+			for _, item := range strings.Split(value, " ") {
+				if NormalizeValue(slot, item) == informed {
+					return true
+				}
+			}
+			continue
+		}
+		if slot == "电话" && containsPhones(value, raw) {
+			return true
+		}
+		if NormalizeValue(slot, value) == informed {
+			return true
+		}
+		if numericSlots[slot] && inRange(slot, informed, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// containsPhones if every informed phone number is one of the expected, some places have several numbers
+func containsPhones(expected string, informed string) bool {
+	numbers := make(map[string]bool)
+	for _, number := range splitPhones(expected) {
+		numbers[number] = true
+	}
+	informedNumbers := splitPhones(informed)
+	for _, number := range informedNumbers {
+		if !numbers[number] {
+			return false
+		}
+	}
+	return len(informedNumbers) > 0
+}
+
+func splitPhones(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool {
+		return unicode.IsSpace(r) || strings.ContainsRune(",，、;；/", r)
+	})
+}
+
+// inRange if the informed value is a range like "4.5分以上" or "100-150元" which contains the expected value
+func inRange(slot string, informed string, value string) bool {
+	if _, err := strconv.ParseFloat(informed, 64); err == nil {
+		return false
+	}
+	constraint, err := database.NormalizeConstraint(slot, informed)
+	if err != nil {
+		return false
+	}
+	r, err := database.ParseRange(constraint)
+	if err != nil {
+		return false
+	}
+	v, err := strconv.ParseFloat(value, 64)
+	return err == nil && r.Contains(v)
+}
+
+// separators which are informed differently from the database, like the commas between phone numbers
+var separatorReplacer = strings.NewReplacer(",", "", "，", "", "、", "", ";", "", "；", "", "（", "(", "）", ")")
+
+// NormalizeValue removes all the white spaces and separators, the units 元 and 分 of numbers, and maps 免费 to 0
+func NormalizeValue(slot string, value string) string {
+	value = strings.Join(strings.Fields(value), "") // \u00a0 is a space too
+	value = separatorReplacer.Replace(value)
+	if !numericSlots[slot] {
+		return value
+	}
+	if value == "免费" || value == "免票" {
+		return "0"
+	}
+	number := strings.TrimSuffix(strings.TrimSuffix(value, "元"), "分")
+	if f, err := strconv.ParseFloat(number, 64); err == nil {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return value
+}
+
+// SortedSlots of the report as domain.slot, for printing
+func (report *InformReport) SortedSlots() []string {
+	var slots []string
+	for domain, counts := range report.Slots {
+		for slot := range counts {
+			slots = append(slots, domain+"."+slot)
+		}
+	}
+	sort.Strings(slots)
+	return slots
+}
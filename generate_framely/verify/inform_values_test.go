@@ -0,0 +1,56 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+)
+
+func TestVerifyInformValues(t *testing.T) {
+	db, dialogues := loadTestData(t)
+	report, err := VerifyInformValues(db, crosswoz.NewSliceIterator(dialogues))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Slots["餐馆"]["人均消费"] == nil || len(report.Mismatches) != 0 {
+		t.Errorf("expect the demo dialogue to agree with the database: %+v", report)
+	}
+
+	// inform a wrong 人均消费 of 大渔铁板烧(蓝色港湾店)
+	var tampered *crosswoz.DialogAct
+	for _, turn := range dialogues[0].Turns {
+		for _, act := range turn.DialogActs {
+			if act.Slot == "人均消费" {
+				tampered = act
+			}
+		}
+	}
+	if tampered == nil {
+		t.Fatal("expect informed 人均消费 in the demo dialogue")
+	}
+	tampered.Value = "100元"
+	report, _ = VerifyInformValues(db, crosswoz.NewSliceIterator(dialogues))
+	if len(report.Mismatches) != 1 || report.Mismatches[0].Expected[0] != "255" || report.Slots["餐馆"]["人均消费"].Mismatched != 1 {
+		t.Errorf("expect the tampered value to be reported: %+v", report.Mismatches)
+	}
+}
+
+func TestNormalizeValue(t *testing.T) {
+	for _, c := range []struct{ slot, value, normalized string }{
+		{"人均消费", "255元", "255"},
+		{"评分", "4.0分", "4"},
+		{"门票", "免费", "0"},
+		{"电话", "010-62754070  010-62756110", "010-62754070010-62756110"},
+		{"地址", "双河南巷3号楼，京开高速长途站东北角", "双河南巷3号楼京开高速长途站东北角"},
+	} {
+		if normalized := NormalizeValue(c.slot, c.value); normalized != c.normalized {
+			t.Errorf("%s %s: expect %s, got %s", c.slot, c.value, c.normalized, normalized)
+		}
+	}
+	if !agrees("评分", "4.5分以上", []string{"4.7"}) || agrees("评分", "4.5分", []string{"4.7"}) {
+		t.Error("expect a range to contain the rating")
+	}
+	if !agrees("电话", "010-69121383", []string{"010-69121383,010-69121226"}) {
+		t.Error("expect one of the phone numbers to agree")
+	}
+}
@@ -0,0 +1,24 @@
+package verify
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"os"
+	"path"
+)
+
+// WriteReport writes any report as outputDir/inputFile/<name>.json
+func WriteReport(report interface{}, inputFile string, outputDir string, name string) error {
+	os.MkdirAll(path.Join(outputDir, inputFile), 0755)
+	b, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	outputFile := path.Join(outputDir, inputFile, name+".json")
+	if err := ioutil.WriteFile(outputFile, b, 0666); err != nil {
+		return err
+	}
+	log.Println("Wrote "+name+" to", outputFile)
+	return nil
+}
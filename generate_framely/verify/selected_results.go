@@ -0,0 +1,129 @@
+package verify
+
+import (
+	"strings"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/database"
+)
+
+// SelectionIssue is a sys turn whose selectedResults contradicts the database
+type SelectionIssue struct {
+	DialogueID   string
+	TurnIdx      int
+	Domain       string
+	Constraints  map[string]string
+	Selected     []string
+	NotInResults []string // selected but not returned by the query
+	Results      int      // number of query results
+	Error        string   `json:",omitempty"` // the constraints can not be queried
+}
+
+// SelectionReport of all the sys turns
+type SelectionReport struct {
+	Dialogues      int
+	SysTurns       int
+	CheckedDomains int // domains with selected results, they are the ones checked
+	Issues         []*SelectionIssue
+	IssuesByDomain map[string]int
+}
+
+// VerifySelectedResults rebuilds the query from the sys_state (or sys_state_init) of each sys turn
+// and checks that the selectedResults of every domain are in the query results
+func VerifySelectedResults(db *database.DB, it crosswoz.DialogueIterator, useInitState bool) (*SelectionReport, error) {
+	report := &SelectionReport{
+		IssuesByDomain: make(map[string]int),
+	}
+	err := crosswoz.ForEachDialogue(it, nil, func(dialogue *crosswoz.Dialogue) {
+		report.Dialogues++
+		for i, turn := range dialogue.Turns {
+			if turn.Speaker != "sys" {
+				continue
+			}
+			report.SysTurns++
+			state := turn.SysState
+			if useInitState {
+				state = turn.SysStateInit
+			}
+			for _, domain := range state.DomainNames() {
+				issue := verifyDomain(db, state, domain)
+				if issue == nil {
+					continue
+				}
+				report.CheckedDomains++
+				if issue.Error == "" && len(issue.NotInResults) == 0 {
+					continue
+				}
+				issue.DialogueID = dialogue.DialogueID
+				issue.TurnIdx = i
+				report.Issues = append(report.Issues, issue)
+				report.IssuesByDomain[domain]++
+			}
+		}
+	})
+	return report, err
+}
+
+// verifyDomain returns nil if nothing is selected in the domain,
+// otherwise an issue which is empty if the selection agrees with the database
+func verifyDomain(db *database.DB, state crosswoz.SystemState, domain string) *SelectionIssue {
+	selected := state.SelectedResults(domain)
+	if len(selected) == 0 {
+		return nil
+	}
+	issue := &SelectionIssue{
+		Domain:      domain,
+		Constraints: state.Constraints(domain),
+		Selected:    selected,
+	}
+	names, results, err := queryResultNames(db, domain, issue.Constraints)
+	if err != nil {
+		issue.Error = err.Error()
+		return issue
+	}
+	issue.Results = results
+	for _, name := range selected {
+		if !names[strings.TrimSpace(name)] {
+			issue.NotInResults = append(issue.NotInResults, name)
+		}
+	}
+	return issue
+}
+
+// queryResultNames are the results written as in selectedResults,
+// "(起点) XX" and "(终点) YY" for 地铁, "出租 (XX - YY)" for 出租
+func queryResultNames(db *database.DB, domain string, constraints map[string]string) (map[string]bool, int, error) {
+	names := make(map[string]bool)
+	switch domain {
+	case database.DomainMetro:
+		cnt := 0
+		for _, end := range []struct{ slot, prefix string }{{"出发地", "(起点) "}, {"目的地", "(终点) "}} {
+			results, err := db.Query(domain, map[string]string{end.slot: constraints[end.slot]})
+			if err != nil {
+				return nil, 0, err
+			}
+			for _, result := range results {
+				names[end.prefix+result.EntityName()] = true
+			}
+			cnt += len(results)
+		}
+		return names, cnt, nil
+	case database.DomainTaxi:
+		results, err := db.Query(domain, constraints)
+		if err != nil {
+			return nil, 0, err
+		}
+		if len(results) > 0 {
+			names["出租 ("+constraints["出发地"]+" - "+constraints["目的地"]+")"] = true
+		}
+		return names, len(results), nil
+	}
+	results, err := db.Query(domain, constraints)
+	if err != nil {
+		return nil, 0, err
+	}
+	for _, result := range results {
+		names[result.EntityName()] = true
+	}
+	return names, len(results), nil
+}
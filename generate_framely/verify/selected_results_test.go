@@ -0,0 +1,53 @@
+package verify
+
+import (
+	"testing"
+
+	"github.com/naturali/CrossWOZ/generate_framely/crosswoz"
+	"github.com/naturali/CrossWOZ/generate_framely/database"
+)
+
+const (
+	dbDir    = "../../data/crosswoz/database"
+	demoFile = "../../data/crosswoz/demo10034.json"
+)
+
+func loadTestData(t *testing.T) (*database.DB, []*crosswoz.Dialogue) {
+	db, err := database.Load(dbDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dialogues, err := crosswoz.LoadDialogues(demoFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, dialogues
+}
+
+func TestVerifySelectedResults(t *testing.T) {
+	db, dialogues := loadTestData(t)
+	report, err := VerifySelectedResults(db, crosswoz.NewSliceIterator(dialogues), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.CheckedDomains == 0 || len(report.Issues) != 0 {
+		t.Errorf("expect the demo dialogue to agree with the database: %+v", report)
+	}
+
+	// select a restaurant which does not satisfy the constraints
+	var tampered *crosswoz.DomainState
+	for _, turn := range dialogues[0].Turns {
+		if turn.Speaker == "sys" && len(turn.SysState.SelectedResults("餐馆")) > 0 {
+			tampered = turn.SysState.Domain("餐馆")
+			break
+		}
+	}
+	if tampered == nil {
+		t.Fatal("expect selected 餐馆 in the demo dialogue")
+	}
+	tampered.SelectedResults = []string{"不存在的餐馆"}
+	report, _ = VerifySelectedResults(db, crosswoz.NewSliceIterator(dialogues), false)
+	if len(report.Issues) == 0 || report.Issues[0].NotInResults[0] != "不存在的餐馆" || report.IssuesByDomain["餐馆"] == 0 {
+		t.Errorf("expect the tampered selection to be reported: %+v", report)
+	}
+}